@@ -2,15 +2,25 @@ package main
 
 import (
 	"advanced-backend/databaseutil"
+	"advanced-backend/internal"
+	"advanced-backend/internal/attachment"
 	"advanced-backend/internal/auth"
+	"advanced-backend/internal/comment"
 	"advanced-backend/internal/config"
 	"advanced-backend/internal/cors"
 	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/label"
+	"advanced-backend/internal/project"
+	"advanced-backend/internal/search"
+	"advanced-backend/internal/storage"
 	"advanced-backend/internal/task"
+	"advanced-backend/internal/template"
+	"advanced-backend/internal/timeentry"
 	"advanced-backend/internal/user"
+	"advanced-backend/internal/view"
+	"advanced-backend/internal/workspace"
 	"context"
 	"errors"
-	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"log"
@@ -58,40 +68,156 @@ func main() {
 	}
 	defer dbPool.Close()
 
-	validator := validator.New()
+	validator := internal.NewValidator()
+
+	var blobStore storage.BlobStore
+	switch cfg.StorageBackend {
+	case "s3":
+		blobStore, err = storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	default:
+		blobStore, err = storage.NewLocalStore(cfg.StoragePath)
+	}
+	if err != nil {
+		logger.Fatal("Failed to create attachment storage", zap.Error(err))
+	}
 
-	taskService := task.NewService(logger, dbPool)
-	userService := user.NewService(logger, dbPool)
+	taskService := task.NewService(logger, dbPool, blobStore)
+	userService := user.NewService(logger, dbPool, blobStore, cfg.BaseURL)
+	projectService := project.NewService(logger, dbPool)
+	workspaceService := workspace.NewService(logger, dbPool)
+	commentService := comment.NewService(logger, dbPool)
+	labelService := label.NewService(logger, dbPool)
+	searchService := search.NewService(logger, dbPool)
+	viewService := view.NewService(logger, dbPool)
+	timeEntryService := timeentry.NewService(logger, dbPool)
+	templateService := template.NewService(logger, dbPool)
+	attachmentService := attachment.NewService(logger, dbPool, blobStore)
 	jwtService := jwt.NewService(logger, 15*time.Minute, 30*time.Minute, dbPool)
 
 	taskHandler := task.NewHandler(logger, validator, taskService)
 	jwtHandler := jwt.NewHandler(logger, jwtService)
 	authHandler := auth.NewHandler(logger, cfg.BaseURL, cfg.GoogleClientID, cfg.GoogleClientSecret, jwtService, userService)
 	userHandler := user.NewHandler(logger, validator, userService)
+	projectHandler := project.NewHandler(logger, validator, projectService, taskService)
+	workspaceHandler := workspace.NewHandler(logger, cfg.BaseURL, validator, workspaceService)
+	commentHandler := comment.NewHandler(logger, validator, commentService, taskService)
+	labelHandler := label.NewHandler(logger, validator, labelService)
+	searchHandler := search.NewHandler(logger, searchService)
+	viewHandler := view.NewHandler(logger, validator, viewService, taskService)
+	timeEntryHandler := timeentry.NewHandler(logger, validator, timeEntryService, taskService)
+	templateHandler := template.NewHandler(logger, validator, templateService, taskService)
+	attachmentSigner := attachment.NewURLSigner(cfg.Secret, cfg.BaseURL, cfg.AttachmentURLTTL)
+	attachmentHandler := attachment.NewHandler(logger, attachmentService, taskService, attachmentSigner, cfg.MaxAttachmentSize)
 
 	jwtMiddleware := jwt.NewMiddleware(logger, jwtService)
 
+	go taskService.RunTrashPurger(context.Background(), time.Hour, cfg.TrashRetention)
+
 	corsMiddleware := cors.NewMiddleware(logger, cfg.AllowOrigins)
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /api/task", taskHandler.GetAll)
-	mux.HandleFunc("GET /api/task/{id}", taskHandler.GetByID)
+	mux.HandleFunc("GET /api/task", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetAll))
+	mux.HandleFunc("GET /api/task/board", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetBoard))
+	mux.HandleFunc("GET /api/task/{id}", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetByID))
 	mux.HandleFunc("POST /api/task", jwtMiddleware.HandlerFunc(taskHandler.Create))
+	mux.HandleFunc("POST /api/task/bulk", jwtMiddleware.HandlerFunc(taskHandler.Bulk))
 	mux.HandleFunc("PUT /api/task/{id}", jwtMiddleware.HandlerFunc(taskHandler.Update))
+	mux.HandleFunc("PATCH /api/task/{id}", jwtMiddleware.HandlerFunc(taskHandler.Patch))
 	mux.HandleFunc("DELETE /api/task/{id}", jwtMiddleware.HandlerFunc(taskHandler.Delete))
+	mux.HandleFunc("GET /api/task/{id}/history", jwtMiddleware.OptionalHandlerFunc(taskHandler.History))
+	mux.HandleFunc("GET /api/task/trash", jwtMiddleware.HandlerFunc(taskHandler.GetTrash))
+	mux.HandleFunc("POST /api/task/{id}/restore", jwtMiddleware.HandlerFunc(taskHandler.Restore))
+	mux.HandleFunc("POST /api/task/{id}/move", jwtMiddleware.HandlerFunc(taskHandler.Move))
+	mux.HandleFunc("PUT /api/task/{id}/assignees/{userId}", jwtMiddleware.HandlerFunc(taskHandler.Assign))
+	mux.HandleFunc("DELETE /api/task/{id}/assignees/{userId}", jwtMiddleware.HandlerFunc(taskHandler.Unassign))
+	mux.HandleFunc("GET /api/task/{id}/subtasks", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetSubtasks))
+	mux.HandleFunc("GET /api/task/{id}/dependencies", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetDependencies))
+	mux.HandleFunc("PUT /api/task/{id}/dependencies/{blockerId}", jwtMiddleware.HandlerFunc(taskHandler.AddDependency))
+	mux.HandleFunc("DELETE /api/task/{id}/dependencies/{blockerId}", jwtMiddleware.HandlerFunc(taskHandler.RemoveDependency))
+	mux.HandleFunc("GET /api/task/{id}/watchers", jwtMiddleware.OptionalHandlerFunc(taskHandler.GetWatchers))
+	mux.HandleFunc("PUT /api/task/{id}/watch", jwtMiddleware.HandlerFunc(taskHandler.Watch))
+	mux.HandleFunc("DELETE /api/task/{id}/watch", jwtMiddleware.HandlerFunc(taskHandler.Unwatch))
+	mux.HandleFunc("GET /api/task/{id}/comments", jwtMiddleware.OptionalHandlerFunc(commentHandler.GetAll))
+	mux.HandleFunc("POST /api/task/{id}/comments", jwtMiddleware.HandlerFunc(commentHandler.Create))
+	mux.HandleFunc("PUT /api/task/{id}/comments/{commentId}", jwtMiddleware.HandlerFunc(commentHandler.Update))
+	mux.HandleFunc("DELETE /api/task/{id}/comments/{commentId}", jwtMiddleware.HandlerFunc(commentHandler.Delete))
+	mux.HandleFunc("POST /api/task/{id}/timer/start", jwtMiddleware.HandlerFunc(timeEntryHandler.StartTimer))
+	mux.HandleFunc("POST /api/task/{id}/timer/stop", jwtMiddleware.HandlerFunc(timeEntryHandler.StopTimer))
+	mux.HandleFunc("GET /api/task/{id}/time-entries", jwtMiddleware.HandlerFunc(timeEntryHandler.GetAll))
+	mux.HandleFunc("POST /api/task/{id}/time-entries", jwtMiddleware.HandlerFunc(timeEntryHandler.Create))
+	mux.HandleFunc("PUT /api/task/{id}/time-entries/{entryId}", jwtMiddleware.HandlerFunc(timeEntryHandler.Update))
+	mux.HandleFunc("DELETE /api/task/{id}/time-entries/{entryId}", jwtMiddleware.HandlerFunc(timeEntryHandler.Delete))
+	mux.HandleFunc("GET /api/time-report", jwtMiddleware.HandlerFunc(timeEntryHandler.Report))
+	mux.HandleFunc("GET /api/task/{id}/attachments", jwtMiddleware.OptionalHandlerFunc(attachmentHandler.GetAll))
+	mux.HandleFunc("POST /api/task/{id}/attachments", jwtMiddleware.HandlerFunc(attachmentHandler.Create))
+	mux.HandleFunc("GET /api/task/{id}/attachments/{attachmentId}", jwtMiddleware.OptionalHandlerFunc(attachmentHandler.GetByID))
+	mux.HandleFunc("DELETE /api/task/{id}/attachments/{attachmentId}", jwtMiddleware.HandlerFunc(attachmentHandler.Delete))
+	mux.HandleFunc("GET /api/attachments/{id}/download", attachmentHandler.Download)
 
 	mux.HandleFunc("GET /api/login/google", authHandler.Login)
 	mux.HandleFunc("GET /api/oauth/google/callback", authHandler.Callback)
 	mux.HandleFunc("GET /api/logout", jwtMiddleware.HandlerFunc(authHandler.Logout))
 	mux.HandleFunc("GET /api/refreshToken/{refreshToken}", jwtHandler.RefreshToken)
 
+	mux.HandleFunc("GET /api/projects", jwtMiddleware.HandlerFunc(projectHandler.GetAll))
+	mux.HandleFunc("GET /api/projects/{id}", jwtMiddleware.HandlerFunc(projectHandler.GetByID))
+	mux.HandleFunc("POST /api/projects", jwtMiddleware.HandlerFunc(projectHandler.Create))
+	mux.HandleFunc("PUT /api/projects/{id}", jwtMiddleware.HandlerFunc(projectHandler.Update))
+	mux.HandleFunc("DELETE /api/projects/{id}", jwtMiddleware.HandlerFunc(projectHandler.Delete))
+	mux.HandleFunc("GET /api/projects/{id}/tasks", jwtMiddleware.HandlerFunc(projectHandler.GetTasks))
+	mux.HandleFunc("GET /api/projects/{id}/workflow", jwtMiddleware.HandlerFunc(projectHandler.GetWorkflow))
+	mux.HandleFunc("PUT /api/projects/{id}/workflow", jwtMiddleware.HandlerFunc(projectHandler.SetWorkflow))
+
+	mux.HandleFunc("GET /api/labels", jwtMiddleware.HandlerFunc(labelHandler.GetAll))
+	mux.HandleFunc("GET /api/labels/{id}", jwtMiddleware.HandlerFunc(labelHandler.GetByID))
+	mux.HandleFunc("POST /api/labels", jwtMiddleware.HandlerFunc(labelHandler.Create))
+	mux.HandleFunc("PUT /api/labels/{id}", jwtMiddleware.HandlerFunc(labelHandler.Update))
+	mux.HandleFunc("DELETE /api/labels/{id}", jwtMiddleware.HandlerFunc(labelHandler.Delete))
+	mux.HandleFunc("POST /api/labels/{id}/merge", jwtMiddleware.HandlerFunc(labelHandler.Merge))
+	mux.HandleFunc("GET /api/search", jwtMiddleware.OptionalHandlerFunc(searchHandler.Search))
+	mux.HandleFunc("GET /api/views", jwtMiddleware.HandlerFunc(viewHandler.GetAll))
+	mux.HandleFunc("GET /api/views/{id}", jwtMiddleware.HandlerFunc(viewHandler.GetByID))
+	mux.HandleFunc("POST /api/views", jwtMiddleware.HandlerFunc(viewHandler.Create))
+	mux.HandleFunc("PUT /api/views/{id}", jwtMiddleware.HandlerFunc(viewHandler.Update))
+	mux.HandleFunc("DELETE /api/views/{id}", jwtMiddleware.HandlerFunc(viewHandler.Delete))
+	mux.HandleFunc("GET /api/views/{id}/tasks", jwtMiddleware.HandlerFunc(viewHandler.GetTasks))
+
+	mux.HandleFunc("GET /api/templates", jwtMiddleware.HandlerFunc(templateHandler.GetAll))
+	mux.HandleFunc("GET /api/templates/{id}", jwtMiddleware.HandlerFunc(templateHandler.GetByID))
+	mux.HandleFunc("POST /api/templates", jwtMiddleware.HandlerFunc(templateHandler.Create))
+	mux.HandleFunc("PUT /api/templates/{id}", jwtMiddleware.HandlerFunc(templateHandler.Update))
+	mux.HandleFunc("DELETE /api/templates/{id}", jwtMiddleware.HandlerFunc(templateHandler.Delete))
+	mux.HandleFunc("POST /api/templates/{id}/instantiate", jwtMiddleware.HandlerFunc(templateHandler.Instantiate))
+
+	mux.HandleFunc("GET /api/workspaces", jwtMiddleware.HandlerFunc(workspaceHandler.GetAll))
+	mux.HandleFunc("GET /api/workspaces/{id}", jwtMiddleware.HandlerFunc(workspaceHandler.GetByID))
+	mux.HandleFunc("POST /api/workspaces", jwtMiddleware.HandlerFunc(workspaceHandler.Create))
+	mux.HandleFunc("PUT /api/workspaces/{id}", jwtMiddleware.HandlerFunc(workspaceHandler.Update))
+	mux.HandleFunc("DELETE /api/workspaces/{id}", jwtMiddleware.HandlerFunc(workspaceHandler.Delete))
+	mux.HandleFunc("GET /api/workspaces/{id}/members", jwtMiddleware.HandlerFunc(workspaceHandler.GetMembers))
+	mux.HandleFunc("PUT /api/workspaces/{id}/members/{userId}", jwtMiddleware.HandlerFunc(workspaceHandler.UpdateMember))
+	mux.HandleFunc("DELETE /api/workspaces/{id}/members/{userId}", jwtMiddleware.HandlerFunc(workspaceHandler.RemoveMember))
+	mux.HandleFunc("GET /api/workspaces/{id}/invitations", jwtMiddleware.HandlerFunc(workspaceHandler.GetInvitations))
+	mux.HandleFunc("POST /api/workspaces/{id}/invitations", jwtMiddleware.HandlerFunc(workspaceHandler.Invite))
+	mux.HandleFunc("DELETE /api/workspaces/{id}/invitations/{invitationId}", jwtMiddleware.HandlerFunc(workspaceHandler.RevokeInvitation))
+	mux.HandleFunc("POST /api/invitations/{id}/accept", jwtMiddleware.HandlerFunc(workspaceHandler.AcceptInvitation))
+
 	mux.HandleFunc("GET /api/user/me", jwtMiddleware.HandlerFunc(userHandler.GetMe))
-	mux.HandleFunc("PUT /api/users", jwtMiddleware.HandlerFunc(userHandler.Update))
+	mux.HandleFunc("PATCH /api/user/me", jwtMiddleware.HandlerFunc(userHandler.Update))
+	mux.HandleFunc("PUT /api/user/me/avatar", jwtMiddleware.HandlerFunc(userHandler.UploadAvatar))
+	mux.HandleFunc("DELETE /api/user/me/avatar", jwtMiddleware.HandlerFunc(userHandler.DeleteAvatar))
+	mux.HandleFunc("GET /api/users/{id}/avatar", userHandler.GetAvatar)
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: corsMiddleware.HandlerFunc(mux.ServeHTTP),
+		Handler: corsMiddleware.HandlerFunc(internal.WithMaxRequestBodySize(mux.ServeHTTP, cfg.MaxRequestBodySize)),
 	}
 
 	logger.Info("Backend started on :8080")
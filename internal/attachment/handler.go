@@ -0,0 +1,359 @@
+package attachment
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// sniffSize is how much of the content is used to detect its type
+	sniffSize = 3072
+	// multipartOverhead bounds the multipart headers and boundaries around the file
+	multipartOverhead = 64 << 10
+	maxFileNameLength = 255
+)
+
+var (
+	errFileRequired    = errors.New("multipart field file is required")
+	errFileEmpty       = errors.New("file is empty")
+	errFileTooLarge    = errors.New("file is too large")
+	errUnsupportedType = errors.New("file type is not allowed")
+)
+
+type Response struct {
+	ID          int32     `json:"id"`
+	TaskID      int32     `json:"taskId"`
+	UploaderID  string    `json:"uploaderId"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// URL downloads the attachment without authentication until URLExpiresAt
+	URL          string    `json:"url"`
+	URLExpiresAt time.Time `json:"urlExpiresAt"`
+}
+
+type Store interface {
+	List(ctx context.Context, taskID int32) ([]TaskAttachment, error)
+	GetByID(ctx context.Context, taskID, id int32) (TaskAttachment, error)
+	Create(ctx context.Context, uploaderID uuid.UUID, taskID int32, fileName, contentType string, size int64, body io.Reader) (TaskAttachment, error)
+	Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error
+	Open(ctx context.Context, id int32) (TaskAttachment, io.ReadCloser, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	store     Store
+	taskStore task.Access
+	signer    *URLSigner
+	maxSize   int64
+}
+
+func NewHandler(logger *zap.Logger, store Store, taskStore task.Access, signer *URLSigner, maxSize int64) *Handler {
+	return &Handler{
+		logger:    logger,
+		store:     store,
+		taskStore: taskStore,
+		signer:    signer,
+		maxSize:   maxSize,
+	}
+}
+
+func (h *Handler) newResponse(attachment TaskAttachment, now time.Time) Response {
+	url, expiresAt := h.signer.URL(attachment.ID, now)
+	return Response{
+		ID:           attachment.ID,
+		TaskID:       attachment.TaskID,
+		UploaderID:   attachment.UploaderID.String(),
+		FileName:     attachment.FileName,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		CreatedAt:    attachment.CreatedAt.Time,
+		URL:          url,
+		URLExpiresAt: expiresAt,
+	}
+}
+
+// writeStoreError maps errors returned by the stores and upload errors to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+	case errors.Is(err, ErrNotUploader):
+		http.Error(w, "Only the uploader can delete an attachment", http.StatusForbidden)
+	case errors.Is(err, errFileRequired):
+		http.Error(w, "Multipart field file is required", http.StatusBadRequest)
+	case errors.Is(err, errFileEmpty):
+		http.Error(w, "File is empty", http.StatusBadRequest)
+	case errors.Is(err, errFileTooLarge), errors.As(err, &maxBytesErr):
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, errUnsupportedType):
+		http.Error(w, "File type is not allowed", http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+func attachmentID(w http.ResponseWriter, r *http.Request, name string) (int32, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// upload is a file received from a multipart request, spooled to a temporary file so
+// that its size is known before it reaches the blob store.
+type upload struct {
+	file        *os.File
+	fileName    string
+	contentType string
+	size        int64
+}
+
+func (u upload) Close() {
+	_ = u.file.Close()
+	_ = os.Remove(u.file.Name())
+}
+
+// readUpload reads the part named file of a multipart request. The content type is
+// sniffed from the content rather than taken from the request.
+func (h *Handler) readUpload(w http.ResponseWriter, r *http.Request) (upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return upload{}, errFileRequired
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return upload{}, errFileRequired
+			}
+			return upload{}, err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		fileName := uploadFileName(part.FileName())
+
+		file, err := os.CreateTemp("", "attachment-*")
+		if err != nil {
+			return upload{}, err
+		}
+		result := upload{file: file, fileName: fileName}
+
+		// One byte over the limit is enough to tell that the file is too large
+		result.size, err = io.Copy(file, io.LimitReader(part, h.maxSize+1))
+		if err != nil {
+			result.Close()
+			return upload{}, err
+		}
+		if result.size > h.maxSize {
+			result.Close()
+			return upload{}, errFileTooLarge
+		}
+		if result.size == 0 {
+			result.Close()
+			return upload{}, errFileEmpty
+		}
+
+		head := make([]byte, sniffSize)
+		n, err := file.ReadAt(head, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			result.Close()
+			return upload{}, err
+		}
+		contentType, ok := sniffContentType(head[:n])
+		if !ok {
+			result.Close()
+			return upload{}, errUnsupportedType
+		}
+		result.contentType = contentType
+
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			result.Close()
+			return upload{}, err
+		}
+		return result, nil
+	}
+}
+
+// uploadFileName keeps the base name of the file sent by the client.
+func uploadFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "attachment"
+	}
+	// Postgres rejects invalid UTF-8, so the name must not be cut inside a character
+	name = strings.ToValidUTF8(name, "\uFFFD")
+	if len(name) > maxFileNameLength {
+		end := maxFileNameLength
+		for !utf8.RuneStart(name[end]) {
+			end--
+		}
+		name = name[:end]
+	}
+	return name
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.store.List(ctx, taskID)
+	if err != nil {
+		h.logger.Error("Failed to get attachments", zap.Error(err))
+		writeStoreError(w, err, "Failed to get attachments")
+		return
+	}
+
+	now := time.Now()
+	resp := make([]Response, len(attachments))
+	for i, attachment := range attachments {
+		resp[i] = h.newResponse(attachment, now)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// GetByID returns the attachment with a fresh download link.
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+	id, ok := attachmentID(w, r, "attachmentId")
+	if !ok {
+		return
+	}
+
+	attachment, err := h.store.GetByID(ctx, taskID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get attachment by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get attachment")
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, h.newResponse(attachment, time.Now()))
+}
+
+// Create uploads a file sent as the field file of a multipart/form-data request.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+
+	file, err := h.readUpload(w, r)
+	if err != nil {
+		h.logger.Warn("Failed to read upload", zap.Error(err))
+		writeStoreError(w, err, "Failed to read upload")
+		return
+	}
+	defer file.Close()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	attachment, err := h.store.Create(ctx, userID, taskID, file.fileName, file.contentType, file.size, file.file)
+	if err != nil {
+		h.logger.Error("Failed to create attachment", zap.Error(err))
+		writeStoreError(w, err, "Failed to create attachment")
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, h.newResponse(attachment, time.Now()))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+	id, ok := attachmentID(w, r, "attachmentId")
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, taskID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete attachment", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Download serves the content of an attachment to holders of a link signed by URLSigner.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := attachmentID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !h.signer.Verify(id, expires, query.Get("signature"), time.Now()) {
+		http.Error(w, "Download link is invalid or has expired", http.StatusForbidden)
+		return
+	}
+
+	attachment, content, err := h.store.Open(ctx, id)
+	if err != nil {
+		h.logger.Warn("Failed to open attachment", zap.Error(err))
+		writeStoreError(w, err, "Failed to download attachment")
+		return
+	}
+	defer content.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", attachment.ContentType)
+	header.Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		h.logger.Warn("Failed to send attachment", zap.Int32("attachment_id", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+package attachment
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUploadFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"  report.pdf ", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\report.pdf`, "report.pdf"},
+		{"", "attachment"},
+		{"/", "attachment"},
+		{"..", "attachment"},
+		{"r\xffport.pdf", "r\uFFFDport.pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := uploadFileName(tt.name); got != tt.want {
+			t.Errorf("uploadFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUploadFileNameTruncatesOnRuneBoundary(t *testing.T) {
+	// 254 bytes followed by a three-byte character that crosses the limit
+	name := strings.Repeat("a", maxFileNameLength-1) + "€.pdf"
+
+	got := uploadFileName(name)
+	if got != strings.Repeat("a", maxFileNameLength-1) {
+		t.Errorf("got %d bytes ending in %q", len(got), got[len(got)-3:])
+	}
+	if !utf8.ValidString(got) {
+		t.Error("the truncated name is not valid UTF-8")
+	}
+}
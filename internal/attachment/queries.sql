@@ -0,0 +1,24 @@
+-- name: ListByTask :many
+SELECT * FROM task_attachments
+WHERE task_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetByID :one
+SELECT * FROM task_attachments WHERE id = $1 AND task_id = $2;
+
+-- name: GetForDownload :one
+-- Attachments of trashed tasks cannot be downloaded
+SELECT a.* FROM task_attachments a
+JOIN tasks t ON t.id = a.task_id
+WHERE a.id = $1 AND t.deleted_at IS NULL;
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM task_attachments WHERE id = $1 AND task_id = $2 FOR UPDATE;
+
+-- name: Create :one
+INSERT INTO task_attachments (task_id, uploader_id, file_name, content_type, size, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM task_attachments WHERE id = $1;
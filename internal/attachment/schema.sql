@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS task_attachments (
+    id SERIAL NOT NULL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    -- Not cascaded: the content in the blob store has to be removed along with the row
+    uploader_id UUID NOT NULL REFERENCES users(id),
+    file_name TEXT NOT NULL,
+    -- Sniffed from the content, the type sent by the client is ignored
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    -- Key of the content in the blob store
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_attachments_task_id_idx ON task_attachments (task_id, created_at);
@@ -0,0 +1,157 @@
+package attachment
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"io"
+)
+
+var (
+	ErrNotFound = errors.New("attachment not found")
+	// ErrNotUploader is returned when someone other than the uploader deletes an attachment
+	ErrNotUploader = errors.New("only the uploader can delete an attachment")
+)
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+	blobs   storage.BlobStore
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool, blobs storage.BlobStore) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+		blobs:   blobs,
+	}
+}
+
+func (s Service) List(ctx context.Context, taskID int32) ([]TaskAttachment, error) {
+	attachments, err := s.queries.ListByTask(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to list attachments", zap.Error(err))
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (s Service) GetByID(ctx context.Context, taskID, id int32) (TaskAttachment, error) {
+	attachment, err := s.queries.GetByID(ctx, GetByIDParams{
+		ID:     id,
+		TaskID: taskID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskAttachment{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get attachment by ID", zap.Error(err))
+		return TaskAttachment{}, err
+	}
+	return attachment, nil
+}
+
+// Create stores the content in the blob store before recording the attachment, and
+// removes the blob again when the attachment cannot be recorded.
+func (s Service) Create(ctx context.Context, uploaderID uuid.UUID, taskID int32, fileName, contentType string, size int64, body io.Reader) (TaskAttachment, error) {
+	key := fmt.Sprintf("tasks/%d/%s", taskID, uuid.New())
+
+	err := s.blobs.Put(ctx, key, body, size, contentType)
+	if err != nil {
+		s.logger.Error("Failed to store attachment content", zap.Error(err))
+		return TaskAttachment{}, err
+	}
+
+	attachment, err := s.queries.Create(ctx, CreateParams{
+		TaskID:      taskID,
+		UploaderID:  uploaderID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create attachment", zap.Error(err))
+		s.deleteBlob(context.WithoutCancel(ctx), key)
+		return TaskAttachment{}, err
+	}
+
+	s.logger.Info("Created attachment", zap.Int32("task_id", taskID), zap.Int32("attachment_id", attachment.ID))
+	return attachment, nil
+}
+
+// Delete removes the attachment, then its content once the removal is committed.
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error {
+	var attachment TaskAttachment
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		attachment, err = q.GetByIDForUpdate(ctx, GetByIDForUpdateParams{
+			ID:     id,
+			TaskID: taskID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if attachment.UploaderID != actorID {
+			return ErrNotUploader
+		}
+
+		return q.Delete(ctx, id)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete attachment", err)
+		return err
+	}
+
+	s.deleteBlob(context.WithoutCancel(ctx), attachment.StorageKey)
+	s.logger.Info("Deleted attachment", zap.Int32("task_id", taskID), zap.Int32("attachment_id", id))
+	return nil
+}
+
+// Open returns the attachment along with its content for a download. The caller closes
+// the content.
+func (s Service) Open(ctx context.Context, id int32) (TaskAttachment, io.ReadCloser, error) {
+	attachment, err := s.queries.GetForDownload(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskAttachment{}, nil, ErrNotFound
+		}
+		s.logger.Error("Failed to get attachment for download", zap.Error(err))
+		return TaskAttachment{}, nil, err
+	}
+
+	content, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.Error("Attachment content is missing", zap.Int32("attachment_id", id), zap.String("key", attachment.StorageKey))
+			return TaskAttachment{}, nil, ErrNotFound
+		}
+		s.logger.Error("Failed to open attachment content", zap.Error(err))
+		return TaskAttachment{}, nil, err
+	}
+	return attachment, content, nil
+}
+
+// deleteBlob removes content that is no longer referenced. A failure only leaves an
+// orphaned blob behind, so it is logged rather than returned.
+func (s Service) deleteBlob(ctx context.Context, key string) {
+	err := s.blobs.Delete(ctx, key)
+	if err != nil {
+		s.logger.Error("Failed to delete attachment content", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err, ErrNotFound, ErrNotUploader)
+}
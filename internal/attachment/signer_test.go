@@ -0,0 +1,89 @@
+package attachment
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedLink issues a link and returns its expiry and signature as Download reads them.
+func signedLink(t *testing.T, signer *URLSigner, id int32, now time.Time) (int64, string) {
+	t.Helper()
+
+	link, _ := signer.URL(id, now)
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(parsed.Path, "/api/attachments/"+strconv.Itoa(int(id))+"/download") {
+		t.Fatalf("unexpected link path %s", parsed.Path)
+	}
+
+	expires, err := strconv.ParseInt(parsed.Query().Get("expires"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expires, parsed.Query().Get("signature")
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := NewURLSigner("secret", "https://tasks.example.com/", 15*time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires, signature := signedLink(t, signer, 42, now)
+
+	if !signer.Verify(42, expires, signature, now) {
+		t.Error("a fresh link was rejected")
+	}
+	if !signer.Verify(42, expires, signature, now.Add(15*time.Minute)) {
+		t.Error("a link was rejected at its expiry")
+	}
+}
+
+func TestURLSignerVerifyExpired(t *testing.T) {
+	signer := NewURLSigner("secret", "https://tasks.example.com", 15*time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires, signature := signedLink(t, signer, 42, now)
+
+	if signer.Verify(42, expires, signature, now.Add(15*time.Minute+time.Second)) {
+		t.Error("an expired link was accepted")
+	}
+}
+
+func TestURLSignerVerifyTampered(t *testing.T) {
+	signer := NewURLSigner("secret", "https://tasks.example.com", 15*time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires, signature := signedLink(t, signer, 42, now)
+
+	flipped := []byte(signature)
+	if flipped[0] == '0' {
+		flipped[0] = '1'
+	} else {
+		flipped[0] = '0'
+	}
+
+	tests := []struct {
+		name      string
+		id        int32
+		expires   int64
+		signature string
+	}{
+		{"other attachment", 43, expires, signature},
+		{"extended expiry", 42, expires + 3600, signature},
+		{"altered signature", 42, expires, string(flipped)},
+		{"truncated signature", 42, expires, signature[:len(signature)-2]},
+		{"not hex", 42, expires, "zz" + signature[2:]},
+		{"empty signature", 42, expires, ""},
+	}
+	for _, tt := range tests {
+		if signer.Verify(tt.id, tt.expires, tt.signature, now) {
+			t.Errorf("%s: tampered link was accepted", tt.name)
+		}
+	}
+
+	other := NewURLSigner("other secret", "https://tasks.example.com", 15*time.Minute)
+	if other.Verify(42, expires, signature, now) {
+		t.Error("a link signed with another secret was accepted")
+	}
+}
@@ -0,0 +1,40 @@
+package attachment
+
+import (
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// allowedTypes are the content types attachments may have. Types that browsers run, such
+// as HTML and SVG, are left out even though downloads are never rendered inline.
+var allowedTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+	"text/csv",
+	"application/json",
+	"application/zip",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.oasis.opendocument.text",
+	"application/vnd.oasis.opendocument.spreadsheet",
+	"application/vnd.oasis.opendocument.presentation",
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.ms-powerpoint",
+}
+
+// sniffContentType detects the type of the content from its first bytes. It reports
+// false when the type is not allowed.
+func sniffContentType(head []byte) (string, bool) {
+	detected := mimetype.Detect(head)
+	for _, allowed := range allowedTypes {
+		if detected.Is(allowed) {
+			return detected.String(), true
+		}
+	}
+	return detected.String(), false
+}
@@ -0,0 +1,230 @@
+package comment
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type MentionResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+type Response struct {
+	ID             int32             `json:"id"`
+	TaskID         int32             `json:"taskId"`
+	AuthorID       string            `json:"authorId"`
+	AuthorUsername string            `json:"authorUsername"`
+	Body           string            `json:"body"`
+	Mentions       []MentionResponse `json:"mentions"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+}
+
+type ListResponse struct {
+	Comments []Response `json:"comments"`
+	Total    int64      `json:"total"`
+	Limit    int32      `json:"limit"`
+	Offset   int32      `json:"offset"`
+}
+
+// Request carries the Markdown body of a comment.
+type Request struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type Store interface {
+	List(ctx context.Context, taskID int32, page internal.Pagination) ([]Comment, int64, error)
+	Create(ctx context.Context, authorID uuid.UUID, taskID int32, body string) (Comment, error)
+	Update(ctx context.Context, actorID uuid.UUID, taskID, id int32, body string) (Comment, error)
+	Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+	taskStore task.Access
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store, taskStore task.Access) *Handler {
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+		taskStore: taskStore,
+	}
+}
+
+func newResponse(comment Comment) Response {
+	resp := Response{
+		ID:             comment.ID,
+		TaskID:         comment.TaskID,
+		AuthorID:       comment.AuthorID.String(),
+		AuthorUsername: comment.AuthorUsername,
+		Body:           comment.Body,
+		Mentions:       make([]MentionResponse, len(comment.Mentions)),
+		CreatedAt:      comment.CreatedAt.Time,
+		UpdatedAt:      comment.UpdatedAt.Time,
+	}
+	for i, mention := range comment.Mentions {
+		resp.Mentions[i] = MentionResponse{
+			ID:       mention.UserID.String(),
+			Username: mention.Username,
+		}
+	}
+	return resp
+}
+
+// writeStoreError maps errors returned by the stores to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Comment not found", http.StatusNotFound)
+	case errors.Is(err, ErrNotAuthor):
+		http.Error(w, "Only the author can change a comment", http.StatusForbidden)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+func commentID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.Atoi(r.PathValue("commentId"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+
+	page, err := internal.ParsePagination(r)
+	if err != nil {
+		http.Error(w, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	comments, total, err := h.store.List(ctx, taskID, page)
+	if err != nil {
+		h.logger.Error("Failed to list task comments", zap.Error(err))
+		http.Error(w, "Failed to get comments", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListResponse{
+		Comments: make([]Response, len(comments)),
+		Total:    total,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+	}
+	for i, comment := range comments {
+		resp.Comments[i] = newResponse(comment)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+
+	var req Request
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	comment, err := h.store.Create(ctx, userID, taskID, req.Body)
+	if err != nil {
+		h.logger.Error("Failed to create task comment", zap.Error(err))
+		writeStoreError(w, err, "Failed to create comment")
+		return
+	}
+
+	resp := newResponse(comment)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+	id, ok := commentID(w, r)
+	if !ok {
+		return
+	}
+
+	var req Request
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	comment, err := h.store.Update(ctx, userID, taskID, id, req.Body)
+	if err != nil {
+		h.logger.Error("Failed to update task comment", zap.Error(err))
+		writeStoreError(w, err, "Failed to update comment")
+		return
+	}
+
+	resp := newResponse(comment)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+	id, ok := commentID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, taskID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete task comment", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,29 @@
+package comment
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @username at the start of the body or after a character that
+// cannot be part of an email address or another mention.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w][\w.-]*)`)
+
+// ParseMentions returns the usernames mentioned in a Markdown body, in order of first
+// appearance and without duplicates.
+func ParseMentions(body string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		// Punctuation at the end of a sentence is not part of the username
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
@@ -0,0 +1,40 @@
+package comment
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"@alice can you look at this?", []string{"alice"}},
+		{"Thanks @alice and @bob", []string{"alice", "bob"}},
+		{"(@alice)", []string{"alice"}},
+		{"**@alice**", []string{"alice"}},
+		{"@john.doe-smith", []string{"john.doe-smith"}},
+		// Email addresses are not mentions
+		{"Send it to alice@example.com", nil},
+		{"Ask @alice, bob@example.com and @carol", []string{"alice", "carol"}},
+		// Trailing punctuation ends the username
+		{"Done, thanks @alice.", []string{"alice"}},
+		{"@alice, @bob! @carol?", []string{"alice", "bob", "carol"}},
+		{"@alice's review", []string{"alice"}},
+		{"ping @dave--", []string{"dave"}},
+		// Every username is reported once, in order of first appearance
+		{"@bob @alice @bob @alice", []string{"bob", "alice"}},
+		{"@alice.\n\n@alice", []string{"alice"}},
+		{"@", nil},
+		{"@.alice", nil},
+		{"@@alice", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := ParseMentions(tt.body); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseMentions(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+-- name: ListByTask :many
+SELECT sqlc.embed(c), u.username AS author_username
+FROM task_comments c
+JOIN users u ON u.id = c.author_id
+WHERE c.task_id = $1
+ORDER BY c.created_at ASC, c.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: CountByTask :one
+SELECT count(*) FROM task_comments WHERE task_id = $1;
+
+-- name: GetByID :one
+SELECT sqlc.embed(c), u.username AS author_username
+FROM task_comments c
+JOIN users u ON u.id = c.author_id
+WHERE c.id = $1 AND c.task_id = $2;
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM task_comments WHERE id = $1 AND task_id = $2 FOR UPDATE;
+
+-- name: Create :one
+INSERT INTO task_comments (task_id, author_id, body)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: Update :one
+UPDATE task_comments SET body = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM task_comments WHERE id = $1;
+
+-- name: FindUsersByUsername :many
+-- Comments on a workspace task can only mention members of the workspace
+SELECT u.id, u.username
+FROM users u
+JOIN tasks t ON t.id = sqlc.arg(task_id)
+WHERE u.username = ANY(sqlc.arg(usernames)::text[])
+  AND (t.workspace_id IS NULL OR EXISTS (
+    SELECT 1 FROM workspace_members m WHERE m.workspace_id = t.workspace_id AND m.user_id = u.id
+  ));
+
+-- name: AddMention :exec
+INSERT INTO task_comment_mentions (comment_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (comment_id, user_id) DO NOTHING;
+
+-- name: DeleteMentions :exec
+DELETE FROM task_comment_mentions WHERE comment_id = $1;
+
+-- name: ListMentions :many
+SELECT m.comment_id, m.user_id, u.username
+FROM task_comment_mentions m
+JOIN users u ON u.id = m.user_id
+WHERE m.comment_id = ANY(sqlc.arg(comment_ids)::int[])
+ORDER BY m.comment_id ASC, u.username ASC;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS task_comments (
+    id SERIAL NOT NULL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED
+);
+
+CREATE INDEX IF NOT EXISTS task_comments_task_id_idx ON task_comments (task_id, created_at);
+CREATE INDEX IF NOT EXISTS task_comments_search_vector_idx ON task_comments USING GIN (search_vector);
+
+CREATE TABLE IF NOT EXISTS task_comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_comment_mentions_user_id_idx ON task_comment_mentions (user_id);
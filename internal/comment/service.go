@@ -0,0 +1,240 @@
+package comment
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound = errors.New("comment not found")
+	// ErrNotAuthor is returned when someone other than the author changes a comment
+	ErrNotAuthor = errors.New("only the author can change a comment")
+)
+
+// Comment is a task comment together with its author's username and the users it mentions.
+type Comment struct {
+	TaskComment
+	AuthorUsername string
+	Mentions       []ListMentionsRow
+}
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+func (s Service) List(ctx context.Context, taskID int32, page internal.Pagination) ([]Comment, int64, error) {
+	rows, err := s.queries.ListByTask(ctx, ListByTaskParams{
+		TaskID: taskID,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list task comments", zap.Error(err))
+		return nil, 0, err
+	}
+
+	total, err := s.queries.CountByTask(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to count task comments", zap.Error(err))
+		return nil, 0, err
+	}
+
+	ids := make([]int32, len(rows))
+	for i, row := range rows {
+		ids[i] = row.TaskComment.ID
+	}
+
+	mentions, err := s.queries.ListMentions(ctx, ids)
+	if err != nil {
+		s.logger.Error("Failed to list comment mentions", zap.Error(err))
+		return nil, 0, err
+	}
+
+	byComment := make(map[int32][]ListMentionsRow)
+	for _, mention := range mentions {
+		byComment[mention.CommentID] = append(byComment[mention.CommentID], mention)
+	}
+
+	comments := make([]Comment, len(rows))
+	for i, row := range rows {
+		comments[i] = Comment{
+			TaskComment:    row.TaskComment,
+			AuthorUsername: row.AuthorUsername,
+			Mentions:       byComment[row.TaskComment.ID],
+		}
+	}
+	return comments, total, nil
+}
+
+func (s Service) Create(ctx context.Context, authorID uuid.UUID, taskID int32, body string) (Comment, error) {
+	var comment Comment
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		created, err := q.Create(ctx, CreateParams{
+			TaskID:   taskID,
+			AuthorID: authorID,
+			Body:     body,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = saveMentions(ctx, q, taskID, created.ID, body)
+		if err != nil {
+			return err
+		}
+
+		comment, err = getComment(ctx, q, taskID, created.ID)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to create task comment", zap.Error(err))
+		return Comment{}, err
+	}
+
+	s.logger.Info("Created task comment", zap.Int32("task_id", taskID), zap.Int32("comment_id", comment.ID))
+	return comment, nil
+}
+
+// Update replaces the body of a comment and re-resolves its mentions. Only the author
+// can edit a comment.
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, taskID, id int32, body string) (Comment, error) {
+	var comment Comment
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := lockForAuthor(ctx, q, actorID, taskID, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Update(ctx, UpdateParams{
+			ID:   id,
+			Body: body,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteMentions(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = saveMentions(ctx, q, taskID, id, body)
+		if err != nil {
+			return err
+		}
+
+		comment, err = getComment(ctx, q, taskID, id)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to update task comment", err)
+		return Comment{}, err
+	}
+	return comment, nil
+}
+
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := lockForAuthor(ctx, q, actorID, taskID, id)
+		if err != nil {
+			return err
+		}
+
+		return q.Delete(ctx, id)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete task comment", err)
+		return err
+	}
+
+	s.logger.Info("Deleted task comment", zap.Int32("task_id", taskID), zap.Int32("comment_id", id))
+	return nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err, ErrNotFound, ErrNotAuthor)
+}
+
+// lockForAuthor locks the comment for the rest of the transaction and checks that the
+// actor wrote it.
+func lockForAuthor(ctx context.Context, q *Queries, actorID uuid.UUID, taskID, id int32) error {
+	comment, err := q.GetByIDForUpdate(ctx, GetByIDForUpdateParams{
+		ID:     id,
+		TaskID: taskID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if comment.AuthorID != actorID {
+		return ErrNotAuthor
+	}
+	return nil
+}
+
+// saveMentions links the comment to every mentioned username that belongs to a user who
+// can see the task. Other usernames are left as plain text.
+func saveMentions(ctx context.Context, q *Queries, taskID, commentID int32, body string) error {
+	usernames := ParseMentions(body)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := q.FindUsersByUsername(ctx, FindUsersByUsernameParams{
+		TaskID:    taskID,
+		Usernames: usernames,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err = q.AddMention(ctx, AddMentionParams{
+			CommentID: commentID,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getComment(ctx context.Context, q *Queries, taskID, id int32) (Comment, error) {
+	row, err := q.GetByID(ctx, GetByIDParams{
+		ID:     id,
+		TaskID: taskID,
+	})
+	if err != nil {
+		return Comment{}, err
+	}
+
+	mentions, err := q.ListMentions(ctx, []int32{id})
+	if err != nil {
+		return Comment{}, err
+	}
+
+	return Comment{
+		TaskComment:    row.TaskComment,
+		AuthorUsername: row.AuthorUsername,
+		Mentions:       mentions,
+	}, nil
+}
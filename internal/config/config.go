@@ -5,7 +5,9 @@ import (
 	"flag"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -15,7 +17,13 @@ import (
 const DefaultSecret = "default-secret"
 
 var (
-	ErrDatabaseURLRequired = errors.New("database_url is required")
+	ErrDatabaseURLRequired       = errors.New("database_url is required")
+	ErrInvalidMaxRequestBodySize = errors.New("max_request_body_size must be positive")
+	ErrInvalidTrashRetention     = errors.New("trash_retention must be positive")
+	ErrInvalidStorageBackend     = errors.New("storage_backend must be local or s3")
+	ErrS3BucketRequired          = errors.New("s3_endpoint and s3_bucket are required for the s3 storage backend")
+	ErrInvalidMaxAttachmentSize  = errors.New("max_attachment_size must be positive")
+	ErrInvalidAttachmentURLTTL   = errors.New("attachment_url_ttl must be positive")
 )
 
 type PresetUserInfo struct {
@@ -23,16 +31,27 @@ type PresetUserInfo struct {
 }
 
 type Config struct {
-	Debug              bool     `yaml:"debug"              envconfig:"DEBUG"`
-	Host               string   `yaml:"host"               envconfig:"HOST"`
-	Port               string   `yaml:"port"               envconfig:"PORT"`
-	BaseURL            string   `yaml:"base_url"          envconfig:"BASE_URL"`
-	Secret             string   `yaml:"secret"             envconfig:"SECRET"`
-	GoogleClientID     string   `yaml:"google_client_id"   envconfig:"GOOGLE_CLIENT_ID"`
-	GoogleClientSecret string   `yaml:"google_client_secret"       envconfig:"GOOGLE_CLIENT_SECRET"`
-	DatabaseURL        string   `yaml:"database_url"       envconfig:"DATABASE_URL"`
-	MigrationSource    string   `yaml:"migration_source"   envconfig:"MIGRATION_SOURCE"`
-	AllowOrigins       []string `yaml:"allow_origins"      envconfig:"ALLOW_ORIGINS"`
+	Debug              bool          `yaml:"debug"              envconfig:"DEBUG"`
+	Host               string        `yaml:"host"               envconfig:"HOST"`
+	Port               string        `yaml:"port"               envconfig:"PORT"`
+	BaseURL            string        `yaml:"base_url"          envconfig:"BASE_URL"`
+	Secret             string        `yaml:"secret"             envconfig:"SECRET"`
+	GoogleClientID     string        `yaml:"google_client_id"   envconfig:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string        `yaml:"google_client_secret"       envconfig:"GOOGLE_CLIENT_SECRET"`
+	DatabaseURL        string        `yaml:"database_url"       envconfig:"DATABASE_URL"`
+	MigrationSource    string        `yaml:"migration_source"   envconfig:"MIGRATION_SOURCE"`
+	AllowOrigins       []string      `yaml:"allow_origins"      envconfig:"ALLOW_ORIGINS"`
+	MaxRequestBodySize int64         `yaml:"max_request_body_size" envconfig:"MAX_REQUEST_BODY_SIZE"`
+	TrashRetention     time.Duration `yaml:"trash_retention"    envconfig:"TRASH_RETENTION"`
+	StorageBackend     string        `yaml:"storage_backend"      envconfig:"STORAGE_BACKEND"`
+	StoragePath        string        `yaml:"storage_path"         envconfig:"STORAGE_PATH"`
+	S3Endpoint         string        `yaml:"s3_endpoint"          envconfig:"S3_ENDPOINT"`
+	S3Region           string        `yaml:"s3_region"            envconfig:"S3_REGION"`
+	S3Bucket           string        `yaml:"s3_bucket"            envconfig:"S3_BUCKET"`
+	S3AccessKeyID      string        `yaml:"s3_access_key_id"     envconfig:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey  string        `yaml:"s3_secret_access_key" envconfig:"S3_SECRET_ACCESS_KEY"`
+	MaxAttachmentSize  int64         `yaml:"max_attachment_size"  envconfig:"MAX_ATTACHMENT_SIZE"`
+	AttachmentURLTTL   time.Duration `yaml:"attachment_url_ttl"   envconfig:"ATTACHMENT_URL_TTL"`
 }
 
 type LogBuffer struct {
@@ -77,6 +96,32 @@ func (c *Config) Validate() error {
 		return ErrDatabaseURLRequired
 	}
 
+	if c.MaxRequestBodySize <= 0 {
+		return ErrInvalidMaxRequestBodySize
+	}
+
+	if c.TrashRetention <= 0 {
+		return ErrInvalidTrashRetention
+	}
+
+	switch c.StorageBackend {
+	case "local":
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return ErrS3BucketRequired
+		}
+	default:
+		return ErrInvalidStorageBackend
+	}
+
+	if c.MaxAttachmentSize <= 0 {
+		return ErrInvalidMaxAttachmentSize
+	}
+
+	if c.AttachmentURLTTL <= 0 {
+		return ErrInvalidAttachmentURLTTL
+	}
+
 	return nil
 }
 
@@ -84,12 +129,18 @@ func Load() (Config, *LogBuffer) {
 	logger := NewConfigLogger()
 
 	config := &Config{
-		Debug:           false,
-		Host:            "localhost",
-		Port:            "8080",
-		Secret:          DefaultSecret,
-		DatabaseURL:     "",
-		MigrationSource: "file://internal/database/migrations",
+		Debug:              false,
+		Host:               "localhost",
+		Port:               "8080",
+		Secret:             DefaultSecret,
+		DatabaseURL:        "",
+		MigrationSource:    "file://internal/database/migrations",
+		MaxRequestBodySize: 1 << 20,
+		TrashRetention:     30 * 24 * time.Hour,
+		StorageBackend:     "local",
+		StoragePath:        "data/attachments",
+		MaxAttachmentSize:  10 << 20,
+		AttachmentURLTTL:   15 * time.Minute,
 	}
 
 	var err error
@@ -147,6 +198,46 @@ func FromEnv(config *Config, logger *LogBuffer) (*Config, error) {
 		config.AllowOrigins = strings.Split(allowOrigins, ",")
 	}
 
+	var maxRequestBodySize int64
+	if value := os.Getenv("MAX_REQUEST_BODY_SIZE"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			logger.Warn("Invalid MAX_REQUEST_BODY_SIZE, ignoring", err, map[string]string{"value": value})
+		} else {
+			maxRequestBodySize = parsed
+		}
+	}
+
+	var trashRetention time.Duration
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Warn("Invalid TRASH_RETENTION, ignoring", err, map[string]string{"value": value})
+		} else {
+			trashRetention = parsed
+		}
+	}
+
+	var maxAttachmentSize int64
+	if value := os.Getenv("MAX_ATTACHMENT_SIZE"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			logger.Warn("Invalid MAX_ATTACHMENT_SIZE, ignoring", err, map[string]string{"value": value})
+		} else {
+			maxAttachmentSize = parsed
+		}
+	}
+
+	var attachmentURLTTL time.Duration
+	if value := os.Getenv("ATTACHMENT_URL_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Warn("Invalid ATTACHMENT_URL_TTL, ignoring", err, map[string]string{"value": value})
+		} else {
+			attachmentURLTTL = parsed
+		}
+	}
+
 	envConfig := &Config{
 		Debug:              os.Getenv("DEBUG") == "true",
 		Host:               os.Getenv("HOST"),
@@ -157,6 +248,17 @@ func FromEnv(config *Config, logger *LogBuffer) (*Config, error) {
 		GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
 		DatabaseURL:        os.Getenv("DATABASE_URL"),
 		MigrationSource:    os.Getenv("MIGRATION_SOURCE"),
+		MaxRequestBodySize: maxRequestBodySize,
+		TrashRetention:     trashRetention,
+		StorageBackend:     os.Getenv("STORAGE_BACKEND"),
+		StoragePath:        os.Getenv("STORAGE_PATH"),
+		S3Endpoint:         os.Getenv("S3_ENDPOINT"),
+		S3Region:           os.Getenv("S3_REGION"),
+		S3Bucket:           os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:      os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey:  os.Getenv("S3_SECRET_ACCESS_KEY"),
+		MaxAttachmentSize:  maxAttachmentSize,
+		AttachmentURLTTL:   attachmentURLTTL,
 	}
 
 	return Merge[Config](config, envConfig)
@@ -174,6 +276,17 @@ func FromFlags(config *Config) (*Config, error) {
 	flag.StringVar(&flagConfig.GoogleClientSecret, "google_client_secret", "", "google client secret")
 	flag.StringVar(&flagConfig.DatabaseURL, "database_url", "", "database url")
 	flag.StringVar(&flagConfig.MigrationSource, "migration_source", "", "migration source")
+	flag.Int64Var(&flagConfig.MaxRequestBodySize, "max_request_body_size", 0, "max request body size in bytes")
+	flag.DurationVar(&flagConfig.TrashRetention, "trash_retention", 0, "how long deleted tasks are kept before being purged")
+	flag.StringVar(&flagConfig.StorageBackend, "storage_backend", "", "where attachments are stored, local or s3")
+	flag.StringVar(&flagConfig.StoragePath, "storage_path", "", "directory of the local attachment storage")
+	flag.StringVar(&flagConfig.S3Endpoint, "s3_endpoint", "", "s3 endpoint url")
+	flag.StringVar(&flagConfig.S3Region, "s3_region", "", "s3 region")
+	flag.StringVar(&flagConfig.S3Bucket, "s3_bucket", "", "s3 bucket")
+	flag.StringVar(&flagConfig.S3AccessKeyID, "s3_access_key_id", "", "s3 access key id")
+	flag.StringVar(&flagConfig.S3SecretAccessKey, "s3_secret_access_key", "", "s3 secret access key")
+	flag.Int64Var(&flagConfig.MaxAttachmentSize, "max_attachment_size", 0, "max attachment size in bytes")
+	flag.DurationVar(&flagConfig.AttachmentURLTTL, "attachment_url_ttl", 0, "how long attachment download links stay valid")
 
 	flag.Parse()
 
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+DROP COLUMN auto_complete,
+DROP COLUMN parent_id;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+ADD COLUMN parent_id INTEGER REFERENCES tasks(id) ON DELETE SET NULL,
+ADD COLUMN auto_complete BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
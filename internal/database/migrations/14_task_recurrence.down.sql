@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+DROP COLUMN previous_occurrence_id,
+DROP COLUMN occurrence,
+DROP COLUMN recurrence;
@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+ADD COLUMN recurrence TEXT,
+ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 1,
+ADD COLUMN previous_occurrence_id INTEGER UNIQUE REFERENCES tasks(id) ON DELETE SET NULL;
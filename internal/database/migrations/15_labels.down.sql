@@ -0,0 +1,16 @@
+ALTER TABLE tasks
+ADD COLUMN labels TEXT[] DEFAULT ARRAY[]::TEXT[];
+
+UPDATE tasks t
+SET labels = l.names
+FROM (
+    SELECT tl.task_id, array_agg(lb.name ORDER BY lower(lb.name)) AS names
+    FROM task_labels tl
+    JOIN labels lb ON lb.id = tl.label_id
+    GROUP BY tl.task_id
+) l
+WHERE l.task_id = t.id;
+
+DROP TABLE IF EXISTS task_labels;
+
+DROP TABLE IF EXISTS labels;
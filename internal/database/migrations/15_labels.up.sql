@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS labels (
+    id SERIAL NOT NULL PRIMARY KEY,
+    -- Labels outside workspaces are shared, like the tasks they belong to
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#808080',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Names are unique per workspace regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS labels_workspace_name_idx ON labels (COALESCE(workspace_id, 0), lower(name));
+
+CREATE TABLE IF NOT EXISTS task_labels (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
+
+-- Labels that only differ in case or surrounding spaces become one label
+INSERT INTO labels (workspace_id, name)
+SELECT DISTINCT ON (COALESCE(t.workspace_id, 0), lower(btrim(l.name))) t.workspace_id, btrim(l.name)
+FROM tasks t
+CROSS JOIN LATERAL unnest(t.labels) AS l(name)
+WHERE btrim(l.name) <> ''
+ORDER BY COALESCE(t.workspace_id, 0), lower(btrim(l.name)), btrim(l.name);
+
+INSERT INTO task_labels (task_id, label_id)
+SELECT DISTINCT t.id, lb.id
+FROM tasks t
+CROSS JOIN LATERAL unnest(t.labels) AS l(name)
+JOIN labels lb ON COALESCE(lb.workspace_id, 0) = COALESCE(t.workspace_id, 0) AND lower(lb.name) = lower(btrim(l.name));
+
+ALTER TABLE tasks
+DROP COLUMN labels;
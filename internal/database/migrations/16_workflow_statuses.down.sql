@@ -0,0 +1,20 @@
+CREATE TYPE task_status AS ENUM ('INBOX', 'TO_DO', 'IN_PROGRESS', 'DONE');
+
+DROP INDEX IF EXISTS tasks_project_id_status_idx;
+
+-- Custom statuses fall back to the default status of their category
+UPDATE tasks
+SET status = CASE status_category WHEN 'DOING' THEN 'IN_PROGRESS' WHEN 'DONE' THEN 'DONE' ELSE 'TO_DO' END
+WHERE status NOT IN ('INBOX', 'TO_DO', 'IN_PROGRESS', 'DONE');
+
+ALTER TABLE tasks
+ALTER COLUMN status DROP DEFAULT,
+ALTER COLUMN status TYPE task_status USING status::task_status,
+ALTER COLUMN status SET DEFAULT 'INBOX',
+DROP COLUMN status_category;
+
+DROP TABLE IF EXISTS task_status_transitions;
+
+DROP TABLE IF EXISTS task_statuses;
+
+DROP TYPE status_category;
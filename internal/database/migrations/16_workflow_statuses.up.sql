@@ -0,0 +1,41 @@
+CREATE TYPE status_category AS ENUM ('TODO', 'DOING', 'DONE');
+
+CREATE TABLE IF NOT EXISTS task_statuses (
+    id SERIAL NOT NULL PRIMARY KEY,
+    -- Statuses without a project make up the default workflow, which is copied into new projects
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    category status_category NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS task_statuses_project_key_idx ON task_statuses (COALESCE(project_id, 0), key);
+
+-- A workflow without transitions allows moving between any of its statuses
+CREATE TABLE IF NOT EXISTS task_status_transitions (
+    from_status_id INTEGER NOT NULL REFERENCES task_statuses(id) ON DELETE CASCADE,
+    to_status_id INTEGER NOT NULL REFERENCES task_statuses(id) ON DELETE CASCADE,
+    PRIMARY KEY (from_status_id, to_status_id)
+);
+
+INSERT INTO task_statuses (project_id, key, name, category, position)
+SELECT p.id, s.key, s.name, s.category::status_category, s.position
+FROM (VALUES ('INBOX', 'Inbox', 'TODO', 0),
+             ('TO_DO', 'To do', 'TODO', 1),
+             ('IN_PROGRESS', 'In progress', 'DOING', 2),
+             ('DONE', 'Done', 'DONE', 3)) AS s(key, name, category, position)
+CROSS JOIN (SELECT NULL::int AS id UNION ALL SELECT id FROM projects) p;
+
+ALTER TABLE tasks
+ALTER COLUMN status DROP DEFAULT,
+ALTER COLUMN status TYPE TEXT USING status::text,
+ALTER COLUMN status SET DEFAULT 'INBOX',
+ADD COLUMN status_category status_category NOT NULL DEFAULT 'TODO';
+
+UPDATE tasks
+SET status_category = CASE status WHEN 'IN_PROGRESS' THEN 'DOING' WHEN 'DONE' THEN 'DONE' ELSE 'TODO' END::status_category;
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_status_idx ON tasks (project_id, status);
+
+DROP TYPE task_status;
@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+DROP COLUMN rank;
+
+DROP SEQUENCE IF EXISTS tasks_rank_seq;
@@ -0,0 +1,16 @@
+CREATE SEQUENCE IF NOT EXISTS tasks_rank_seq;
+
+-- Ranks order tasks on the board. New tasks get increasing ranks so that they end up at the
+-- bottom of their column, and moved tasks get a rank between their new neighbors, see rankBetween.
+ALTER TABLE tasks
+ADD COLUMN rank TEXT COLLATE "C" NOT NULL DEFAULT lpad(to_hex(nextval('tasks_rank_seq')), 12, '0') || 'i';
+
+-- Keep the existing tasks in creation order
+UPDATE tasks t
+SET rank = r.rank
+FROM (SELECT id, lpad(to_hex(row_number() OVER (ORDER BY id)), 12, '0') || 'i' AS rank FROM tasks) r
+WHERE r.id = t.id;
+
+SELECT setval('tasks_rank_seq', (SELECT count(*) FROM tasks) + 1, false);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_rank_idx ON tasks (rank);
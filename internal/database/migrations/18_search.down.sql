@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS task_comments_search_vector_idx;
+
+ALTER TABLE task_comments
+DROP COLUMN search_vector;
+
+DROP INDEX IF EXISTS tasks_title_trgm_idx;
+DROP INDEX IF EXISTS tasks_search_vector_idx;
+
+ALTER TABLE tasks
+DROP COLUMN search_vector;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS tasks_priority_idx;
+
+ALTER TABLE tasks
+DROP COLUMN time_spent_minutes,
+DROP COLUMN estimate_points,
+DROP COLUMN priority;
+
+DROP TYPE IF EXISTS task_priority;
@@ -0,0 +1,9 @@
+-- P0 is the most urgent, so that sorting by priority lists urgent tasks first
+CREATE TYPE task_priority AS ENUM ('P0', 'P1', 'P2', 'P3');
+
+ALTER TABLE tasks
+ADD COLUMN priority task_priority NOT NULL DEFAULT 'P2',
+ADD COLUMN estimate_points INTEGER CHECK (estimate_points >= 0),
+ADD COLUMN time_spent_minutes INTEGER NOT NULL DEFAULT 0 CHECK (time_spent_minutes >= 0);
+
+CREATE INDEX IF NOT EXISTS tasks_priority_idx ON tasks (priority);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS task_templates (
+    id SERIAL NOT NULL PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Templates in a workspace are visible to its members, the others only to their owner
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    -- Days between the start date given when instantiating and the due date
+    due_offset_days INTEGER,
+    -- See template.Subtask
+    subtasks JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_templates_owner_id_idx ON task_templates (owner_id);
+CREATE INDEX IF NOT EXISTS task_templates_workspace_id_idx ON task_templates (workspace_id);
@@ -0,0 +1,5 @@
+UPDATE users SET avatar_url = oauth_avatar_url;
+ALTER TABLE users
+DROP COLUMN avatar_key;
+ALTER TABLE users
+DROP COLUMN oauth_avatar_url;
@@ -0,0 +1,6 @@
+-- avatar_url becomes the picture shown for the user, the uploaded avatar when there is one
+ALTER TABLE users
+ADD COLUMN oauth_avatar_url VARCHAR(512);
+ALTER TABLE users
+ADD COLUMN avatar_key TEXT;
+UPDATE users SET oauth_avatar_url = avatar_url;
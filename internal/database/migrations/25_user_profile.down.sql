@@ -0,0 +1,8 @@
+ALTER TABLE users
+DROP COLUMN notification_preferences;
+ALTER TABLE users
+DROP COLUMN locale;
+ALTER TABLE users
+DROP COLUMN timezone;
+ALTER TABLE users
+DROP COLUMN display_name;
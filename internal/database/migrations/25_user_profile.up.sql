@@ -0,0 +1,8 @@
+ALTER TABLE users
+ADD COLUMN display_name VARCHAR(100);
+ALTER TABLE users
+ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users
+ADD COLUMN locale TEXT NOT NULL DEFAULT 'en';
+ALTER TABLE users
+ADD COLUMN notification_preferences JSONB NOT NULL DEFAULT '{"mentions": true, "assignments": true, "comments": true, "dueDates": true}';
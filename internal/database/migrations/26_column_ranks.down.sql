@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS tasks_column_rank_idx;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_rank_idx ON tasks (rank);
@@ -0,0 +1,5 @@
+-- Ranks only order tasks within their board column, so they no longer need to be unique
+DROP INDEX IF EXISTS tasks_rank_idx;
+
+CREATE INDEX IF NOT EXISTS tasks_column_rank_idx ON tasks (COALESCE(workspace_id, 0), COALESCE(project_id, 0), status, rank)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,18 @@
+-- The column is still there if the up migration could not convert every task
+ALTER TABLE tasks
+ADD COLUMN IF NOT EXISTS time_spent_minutes INTEGER NOT NULL DEFAULT 0 CHECK (time_spent_minutes >= 0);
+
+-- Turn the entries created from minutes entered by hand back into minutes
+WITH converted AS (
+    DELETE FROM time_entries
+    WHERE note = 'Time spent before time tracking'
+    RETURNING task_id, ended_at - started_at AS spent
+)
+UPDATE tasks t
+SET time_spent_minutes = s.minutes
+FROM (
+    SELECT task_id, (extract(epoch FROM sum(spent)) / 60)::int AS minutes
+    FROM converted
+    GROUP BY task_id
+) s
+WHERE s.task_id = t.id;
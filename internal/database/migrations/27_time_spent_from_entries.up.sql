@@ -0,0 +1,30 @@
+-- Time spent is now the total of the time entries. Minutes entered by hand become an entry
+-- that ends at the task's last update, owned by the first user known to have touched the
+-- task. Tasks created before history was recorded have no CREATE event, so the earliest
+-- event of any kind is used, then the owner of the project or the creator of the workspace.
+INSERT INTO time_entries (task_id, user_id, started_at, ended_at, note)
+SELECT t.id, a.user_id, t.updated_at - make_interval(mins => t.time_spent_minutes), t.updated_at, 'Time spent before time tracking'
+FROM tasks t
+CROSS JOIN LATERAL (
+    SELECT COALESCE(
+        (SELECT e.actor_id FROM task_events e WHERE e.task_id = t.id ORDER BY e.created_at, e.id LIMIT 1),
+        (SELECT p.owner_id FROM projects p WHERE p.id = t.project_id),
+        (SELECT w.created_by FROM workspaces w WHERE w.id = t.workspace_id),
+        (SELECT ta.user_id FROM task_assignees ta WHERE ta.task_id = t.id ORDER BY ta.assigned_at, ta.user_id LIMIT 1)
+    ) AS user_id
+) a
+WHERE t.time_spent_minutes > 0 AND a.user_id IS NOT NULL;
+
+-- Minutes that could not be attributed to anyone are kept until they are converted by hand
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM tasks t
+        WHERE t.time_spent_minutes > 0
+          AND NOT EXISTS (SELECT 1 FROM time_entries e WHERE e.task_id = t.id AND e.note = 'Time spent before time tracking')
+    ) THEN
+        ALTER TABLE tasks DROP COLUMN time_spent_minutes;
+    END IF;
+END
+$$;
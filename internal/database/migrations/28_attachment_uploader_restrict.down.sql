@@ -0,0 +1,3 @@
+ALTER TABLE task_attachments
+DROP CONSTRAINT IF EXISTS task_attachments_uploader_id_fkey,
+ADD CONSTRAINT task_attachments_uploader_id_fkey FOREIGN KEY (uploader_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+-- Deleting a user must not drop attachment rows without removing their blobs
+ALTER TABLE task_attachments
+DROP CONSTRAINT IF EXISTS task_attachments_uploader_id_fkey,
+ADD CONSTRAINT task_attachments_uploader_id_fkey FOREIGN KEY (uploader_id) REFERENCES users(id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_events;
+DROP TYPE IF EXISTS task_event_action;
\ No newline at end of file
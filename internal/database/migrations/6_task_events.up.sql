@@ -0,0 +1,12 @@
+CREATE TYPE task_event_action AS ENUM ('CREATE', 'UPDATE', 'DELETE');
+
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    actor_id UUID NOT NULL REFERENCES users(id),
+    action task_event_action NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, created_at);
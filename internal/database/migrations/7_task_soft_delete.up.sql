@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TYPE task_event_action ADD VALUE IF NOT EXISTS 'RESTORE';
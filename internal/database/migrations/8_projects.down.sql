@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
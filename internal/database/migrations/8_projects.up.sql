@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL NOT NULL PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#808080',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS projects_owner_id_idx ON projects (owner_id);
+
+ALTER TABLE tasks
+ADD COLUMN project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_idx ON tasks (project_id);
@@ -0,0 +1,7 @@
+ALTER TABLE tasks
+DROP COLUMN workspace_id;
+
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+DROP TYPE IF EXISTS workspace_role;
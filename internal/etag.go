@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPreconditionRequired = errors.New("if-match header is required")
+	ErrPreconditionFailed   = errors.New("if-match header does not name a current version")
+)
+
+// ETag formats a resource version as a strong entity tag. Parts of the representation that
+// change without the version, such as counts derived from other tables, are passed as
+// derived and hashed into the tag, so that caches revalidate when they change.
+func ETag(version int32, derived []byte) string {
+	if len(derived) == 0 {
+		return fmt.Sprintf(`"%d"`, version)
+	}
+	sum := sha256.Sum256(derived)
+	return fmt.Sprintf(`"%d-%x"`, version, sum[:8])
+}
+
+// ParseIfMatch reads the version named by an If-Match header. It reports wildcard as true
+// for "*". Weak or malformed tags can never match and yield ErrPreconditionFailed.
+// The derived part of a tag is ignored, since it does not change through the resource.
+func ParseIfMatch(header string) (version int32, wildcard bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false, ErrPreconditionRequired
+	}
+	if header == "*" {
+		return 0, true, nil
+	}
+
+	// Only a single strong tag is supported
+	if strings.HasPrefix(header, "W/") || strings.Contains(header, ",") {
+		return 0, false, ErrPreconditionFailed
+	}
+
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return 0, false, ErrPreconditionFailed
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, false, ErrPreconditionFailed
+	}
+
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+	parsed, err := strconv.ParseInt(unquoted, 10, 32)
+	if err != nil || parsed <= 0 {
+		return 0, false, ErrPreconditionFailed
+	}
+
+	return int32(parsed), false, nil
+}
+
+// MatchesIfNoneMatch reports whether etag is listed in an If-None-Match header,
+// using the weak comparison RFC 9110 prescribes for that header.
+func MatchesIfNoneMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	if got := ETag(3, nil); got != `"3"` {
+		t.Errorf("got %s, want \"3\"", got)
+	}
+
+	tagged := ETag(3, []byte(`{"comments":1}`))
+	if tagged == ETag(3, []byte(`{"comments":2}`)) {
+		t.Error("a change of the derived details kept the tag")
+	}
+	if tagged != ETag(3, []byte(`{"comments":1}`)) {
+		t.Error("the same details gave different tags")
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		version  int32
+		wildcard bool
+		err      error
+	}{
+		{`"7"`, 7, false, nil},
+		{ETag(7, []byte("details")), 7, false, nil},
+		{"*", 0, true, nil},
+		{"", 0, false, ErrPreconditionRequired},
+		{`W/"7"`, 0, false, ErrPreconditionFailed},
+		{`"7", "8"`, 0, false, ErrPreconditionFailed},
+		{`7`, 0, false, ErrPreconditionFailed},
+		{`"-7"`, 0, false, ErrPreconditionFailed},
+		{`"0"`, 0, false, ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		version, wildcard, err := ParseIfMatch(tt.header)
+		if version != tt.version || wildcard != tt.wildcard || !errors.Is(err, tt.err) {
+			t.Errorf("ParseIfMatch(%q) = %d, %v, %v, want %d, %v, %v",
+				tt.header, version, wildcard, err, tt.version, tt.wildcard, tt.err)
+		}
+	}
+}
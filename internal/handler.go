@@ -1,13 +1,149 @@
 package internal
 
 import (
+	"context"
+	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"io"
+	"mime"
 	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 )
 
+const (
+	DefaultMaxRequestBodySize int64 = 1 << 20
+	MergePatchMediaType             = "application/merge-patch+json"
+)
+
+type maxRequestBodySizeKey struct{}
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrRequestBodyTooLarge  = errors.New("request body too large")
+	ErrInvalidRequestBody   = errors.New("invalid request body")
+)
+
+// UnsupportedMediaTypeError is returned when the request Content-Type does not match
+// what the parser expects. It matches ErrUnsupportedMediaType with errors.Is.
+type UnsupportedMediaTypeError struct {
+	Expected string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("%s: expected %s", ErrUnsupportedMediaType, e.Expected)
+}
+
+func (e *UnsupportedMediaTypeError) Is(target error) bool {
+	return target == ErrUnsupportedMediaType
+}
+
+// RequestBodyTooLargeError is returned when the body exceeds the configured limit.
+// It matches ErrRequestBodyTooLarge with errors.Is.
+type RequestBodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *RequestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("%s: limit is %d bytes", ErrRequestBodyTooLarge, e.Limit)
+}
+
+func (e *RequestBodyTooLargeError) Is(target error) bool {
+	return target == ErrRequestBodyTooLarge
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by ParseRequestBody when the body is well-formed JSON
+// but one or more fields do not satisfy the target struct.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+type ErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// NewValidator returns a validator that reports fields by their JSON names.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+var (
+	ruleMessagesMu sync.RWMutex
+	ruleMessages   = make(map[string]string)
+)
+
+// RegisterRuleMessage sets the message reported for fields that fail the validation tag.
+// Packages that define their own rules use it so that clients get a readable message.
+func RegisterRuleMessage(tag, message string) {
+	ruleMessagesMu.Lock()
+	defer ruleMessagesMu.Unlock()
+	ruleMessages[tag] = message
+}
+
+// RegisterValidation registers fn for tag with v along with the message of its failures.
+func RegisterValidation(v *validator.Validate, tag string, fn validator.Func, message string) error {
+	err := v.RegisterValidation(tag, fn)
+	if err != nil {
+		return err
+	}
+	RegisterRuleMessage(tag, message)
+	return nil
+}
+
+func ruleMessage(tag string) (string, bool) {
+	ruleMessagesMu.RLock()
+	defer ruleMessagesMu.RUnlock()
+	message, ok := ruleMessages[tag]
+	return message, ok
+}
+
+// WithMaxRequestBodySize sets the upper bound, in bytes, for bodies read by
+// ParseRequestBody in next. Requests outside it use DefaultMaxRequestBodySize.
+func WithMaxRequestBodySize(next http.HandlerFunc, limit int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), maxRequestBodySizeKey{}, limit)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func maxRequestBodySize(ctx context.Context) int64 {
+	limit, ok := ctx.Value(maxRequestBodySizeKey{}).(int64)
+	if !ok || limit <= 0 {
+		return DefaultMaxRequestBodySize
+	}
+	return limit
+}
+
 func WriteJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -24,27 +160,200 @@ func WriteJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
+// WriteParseError maps an error returned by ParseRequestBody to a JSON error response.
+func WriteParseError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	var mediaTypeErr *UnsupportedMediaTypeError
+	var tooLargeErr *RequestBodyTooLargeError
+	switch {
+	case errors.As(err, &validationErr):
+		WriteJSONResponse(w, http.StatusBadRequest, ErrorResponse{
+			Message: "Validation failed",
+			Errors:  validationErr.Fields,
+		})
+	case errors.As(err, &mediaTypeErr):
+		if mediaTypeErr.Expected == MergePatchMediaType {
+			w.Header().Set("Accept-Patch", MergePatchMediaType)
+		}
+		WriteJSONResponse(w, http.StatusUnsupportedMediaType, ErrorResponse{
+			Message: fmt.Sprintf("Content-Type must be %s", mediaTypeErr.Expected),
+		})
+	case errors.As(err, &tooLargeErr):
+		WriteJSONResponse(w, http.StatusRequestEntityTooLarge, ErrorResponse{
+			Message: fmt.Sprintf("Request body must not exceed %d bytes", tooLargeErr.Limit),
+		})
+	default:
+		WriteJSONResponse(w, http.StatusBadRequest, ErrorResponse{Message: "Invalid request body"})
+	}
+}
+
 func ParseRequestBody(v *validator.Validate, r *http.Request, s interface{}) error {
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		return err
+	return parseBody(v, r, s, "application/json")
+}
+
+// ParseMergePatch parses an RFC 7396 JSON Merge Patch document into s. Fields of s
+// should be Optional so that omitted members can be told apart from explicit nulls.
+func ParseMergePatch(v *validator.Validate, r *http.Request, s interface{}) error {
+	return parseBody(v, r, s, MergePatchMediaType)
+}
+
+func parseBody(v *validator.Validate, r *http.Request, s interface{}, expectedMediaType string) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != expectedMediaType {
+		return &UnsupportedMediaTypeError{Expected: expectedMediaType}
 	}
+
+	body := http.MaxBytesReader(nil, r.Body, maxRequestBodySize(r.Context()))
 	defer func() {
-		err := r.Body.Close()
-		if err != nil {
-			fmt.Println("Error closing request body:", err)
-		}
+		// The body has been read, so there is nothing to do about a failed close
+		_ = body.Close()
 	}()
 
-	err = json.Unmarshal(bodyBytes, s)
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(s)
 	if err != nil {
-		return err
+		return decodeError(err)
+	}
+
+	// The body must hold exactly one JSON value
+	err = decoder.Decode(&json.RawMessage{})
+	if !errors.Is(err, io.EOF) {
+		if err == nil {
+			return fmt.Errorf("%w: unexpected data after JSON value", ErrInvalidRequestBody)
+		}
+		return decodeError(err)
 	}
 
 	err = v.Struct(s)
 	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			return newValidationError(validationErrors)
+		}
 		return err
 	}
 
 	return nil
 }
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &RequestBodyTooLargeError{Limit: maxBytesErr.Limit}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be of type %s", jsonTypeName(typeErr.Type)),
+		}}}
+	}
+
+	if field, ok := unknownField(err); ok {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   field,
+			Rule:    "unknown",
+			Message: "is not a recognized field",
+		}}}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: body is empty", ErrInvalidRequestBody)
+	}
+
+	return fmt.Errorf("%w: %v", ErrInvalidRequestBody, err)
+}
+
+// unknownField reports the field named by a DisallowUnknownFields decode error.
+// encoding/json has no typed error for it, so this depends on its message format.
+func unknownField(err error) (string, bool) {
+	field, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	return strings.Trim(field, `"`), true
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// jsonTypeName names the JSON type that decodes into t, so messages don't expose Go types.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "value"
+	}
+}
+
+func newValidationError(validationErrors validator.ValidationErrors) *ValidationError {
+	fields := make([]FieldError, len(validationErrors))
+	for i, fe := range validationErrors {
+		// Drop the struct name so nested fields read like "items[0].title"
+		field := fe.Namespace()
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest
+		}
+
+		fields[i] = FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: fieldErrorMessage(fe),
+		}
+	}
+	return &ValidationError{Fields: fields}
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "notnull":
+		return "must not be null"
+	default:
+		if message, ok := ruleMessage(fe.Tag()); ok {
+			return message
+		}
+		return fmt.Sprintf("failed the '%s' rule", fe.Tag())
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type parseTestRequest struct {
+	Title    string           `json:"title" validate:"required"`
+	Priority int              `json:"priority"`
+	Done     bool             `json:"done"`
+	Owner    uuid.UUID        `json:"owner"`
+	Labels   []string         `json:"labels"`
+	Due      *time.Time       `json:"due"`
+	Extra    map[string]int32 `json:"extra"`
+}
+
+func parseTestBody(t *testing.T, body string, limit int64) error {
+	t.Helper()
+
+	var err error
+	handler := WithMaxRequestBodySize(func(w http.ResponseWriter, r *http.Request) {
+		var req parseTestRequest
+		err = ParseRequestBody(NewValidator(), r, &req)
+	}, limit)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	handler(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestUnknownField(t *testing.T) {
+	err := parseTestBody(t, `{"title":"a","colour":"red"}`, DefaultMaxRequestBodySize)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	got := validationErr.Fields[0]
+	if got.Field != "colour" || got.Rule != "unknown" {
+		t.Errorf("got %+v, want field colour with rule unknown", got)
+	}
+}
+
+func TestUnknownFieldIgnoresOtherErrors(t *testing.T) {
+	if _, ok := unknownField(errors.New("json: cannot unmarshal string")); ok {
+		t.Error("expected no unknown field")
+	}
+}
+
+func TestTypeErrorUsesJSONTypeNames(t *testing.T) {
+	tests := []struct {
+		body  string
+		field string
+		want  string
+	}{
+		{`{"title":1}`, "title", "must be of type string"},
+		{`{"title":"a","priority":"high"}`, "priority", "must be of type number"},
+		{`{"title":"a","done":"yes"}`, "done", "must be of type boolean"},
+		{`{"title":"a","owner":12}`, "owner", "must be of type string"},
+		{`{"title":"a","labels":"x"}`, "labels", "must be of type array"},
+		{`{"title":"a","due":true}`, "due", "must be of type string"},
+		{`{"title":"a","extra":[]}`, "extra", "must be of type object"},
+	}
+
+	for _, tt := range tests {
+		err := parseTestBody(t, tt.body, DefaultMaxRequestBodySize)
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected ValidationError, got %v", tt.body, err)
+			continue
+		}
+		got := validationErr.Fields[0]
+		if got.Field != tt.field || got.Message != tt.want {
+			t.Errorf("%s: got %s %q, want %s %q", tt.body, got.Field, got.Message, tt.field, tt.want)
+		}
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	err := parseTestBody(t, `{"title":"`+strings.Repeat("a", 64)+`"}`, 32)
+
+	var tooLargeErr *RequestBodyTooLargeError
+	if !errors.As(err, &tooLargeErr) {
+		t.Fatalf("expected RequestBodyTooLargeError, got %v", err)
+	}
+	if tooLargeErr.Limit != 32 {
+		t.Errorf("got limit %d, want 32", tooLargeErr.Limit)
+	}
+	if !errors.Is(err, ErrRequestBodyTooLarge) {
+		t.Error("expected error to match ErrRequestBodyTooLarge")
+	}
+
+	w := httptest.NewRecorder()
+	WriteParseError(w, err)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "32 bytes") {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+}
+
+type ruleTestRequest struct {
+	Code  string `json:"code" validate:"testcode"`
+	Other string `json:"other" validate:"testother"`
+}
+
+func TestRegisteredRuleMessage(t *testing.T) {
+	v := NewValidator()
+	fail := func(validator.FieldLevel) bool { return false }
+	err := RegisterValidation(v, "testcode", fail, "must be a test code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = v.RegisterValidation("testother", fail)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(v.Struct(ruleTestRequest{}), &validationErrors) {
+		t.Fatal("expected validation errors")
+	}
+	validationErr := newValidationError(validationErrors)
+
+	want := []FieldError{
+		{Field: "code", Rule: "testcode", Message: "must be a test code"},
+		{Field: "other", Rule: "testother", Message: "failed the 'testother' rule"},
+	}
+	if len(validationErr.Fields) != len(want) {
+		t.Fatalf("got %+v, want %+v", validationErr.Fields, want)
+	}
+	for i := range want {
+		if validationErr.Fields[i] != want[i] {
+			t.Errorf("got %+v, want %+v", validationErr.Fields[i], want[i])
+		}
+	}
+}
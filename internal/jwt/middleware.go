@@ -47,3 +47,16 @@ func (m Middleware) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r)
 	}
 }
+
+// OptionalHandlerFunc authenticates the request when an Authorization header is present
+// and lets anonymous requests through without a user in the context.
+func (m Middleware) OptionalHandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.HandlerFunc(next).ServeHTTP(w, r)
+	}
+}
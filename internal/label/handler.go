@@ -0,0 +1,302 @@
+package label
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultColor = "#808080"
+
+type Response struct {
+	ID          int32     `json:"id"`
+	WorkspaceID *int32    `json:"workspaceId"`
+	OwnerID     *string   `json:"ownerId"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type CreateRequest struct {
+	Name        string `json:"name" validate:"required,max=50"`
+	Color       string `json:"color" validate:"omitempty,hexcolor"`
+	WorkspaceID *int32 `json:"workspaceId" validate:"omitempty,gt=0"`
+}
+
+type UpdateRequest struct {
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color" validate:"required,hexcolor"`
+}
+
+type MergeRequest struct {
+	IntoID int32 `json:"intoId" validate:"required,gt=0"`
+}
+
+type Store interface {
+	List(ctx context.Context, viewerID uuid.UUID, workspaceID *int32) ([]Label, error)
+	GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (Label, error)
+	Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, name, color string) (Label, error)
+	Update(ctx context.Context, actorID uuid.UUID, id int32, name, color string) (Label, error)
+	Delete(ctx context.Context, actorID uuid.UUID, id int32) error
+	Merge(ctx context.Context, actorID uuid.UUID, id, intoID int32) (Label, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store) *Handler {
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+	}
+}
+
+func newResponse(label Label) Response {
+	resp := Response{
+		ID:        label.ID,
+		Name:      label.Name,
+		Color:     label.Color,
+		CreatedAt: label.CreatedAt.Time,
+		UpdatedAt: label.UpdatedAt.Time,
+	}
+	if label.WorkspaceID.Valid {
+		resp.WorkspaceID = &label.WorkspaceID.Int32
+	}
+	if label.OwnerID.Valid {
+		ownerID := uuid.UUID(label.OwnerID.Bytes).String()
+		resp.OwnerID = &ownerID
+	}
+	return resp
+}
+
+// writeStoreError maps errors returned by the store to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Label not found", http.StatusNotFound)
+	case errors.Is(err, ErrWorkspaceNotFound):
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Insufficient workspace role", http.StatusForbidden)
+	case errors.Is(err, ErrNameTaken):
+		http.Error(w, "A label with this name already exists", http.StatusConflict)
+	case errors.Is(err, ErrInvalidMerge):
+		http.Error(w, "Labels must be different and belong to the same workspace", http.StatusBadRequest)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// pathLabelID extracts the label ID from the URL, writing a 400 response when it is invalid.
+func pathLabelID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Label ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid label ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// labelName trims the name, writing a 400 response when nothing is left.
+func labelName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		http.Error(w, "Label name is required", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// GetAll lists the labels of the workspace given by ?workspaceId=, or the shared labels
+// used by tasks outside workspaces.
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var workspaceID *int32
+	if value := r.URL.Query().Get("workspaceId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+		workspaceID = new(int32)
+		*workspaceID = int32(id)
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	labels, err := h.store.List(ctx, userID, workspaceID)
+	if err != nil {
+		h.logger.Warn("Failed to get labels", zap.Error(err))
+		writeStoreError(w, err, "Failed to get labels")
+		return
+	}
+
+	resp := make([]Response, len(labels))
+	for i, label := range labels {
+		resp[i] = newResponse(label)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathLabelID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	label, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get label by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get label")
+		return
+	}
+
+	resp := newResponse(label)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := labelName(w, req.Name)
+	if !ok {
+		return
+	}
+	if req.Color == "" {
+		req.Color = DefaultColor
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	label, err := h.store.Create(ctx, userID, req.WorkspaceID, name, req.Color)
+	if err != nil {
+		h.logger.Error("Failed to create label", zap.Error(err))
+		writeStoreError(w, err, "Failed to create label")
+		return
+	}
+
+	resp := newResponse(label)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+// Update renames or recolors a label on every task that has it.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathLabelID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := labelName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	label, err := h.store.Update(ctx, userID, id, name, req.Color)
+	if err != nil {
+		h.logger.Error("Failed to update label", zap.Error(err))
+		writeStoreError(w, err, "Failed to update label")
+		return
+	}
+
+	resp := newResponse(label)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathLabelID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete label", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete label")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Merge folds the label into another one and responds with the label that remains.
+func (h *Handler) Merge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathLabelID(w, r)
+	if !ok {
+		return
+	}
+
+	var req MergeRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	label, err := h.store.Merge(ctx, userID, id, req.IntoID)
+	if err != nil {
+		h.logger.Error("Failed to merge labels", zap.Error(err))
+		writeStoreError(w, err, "Failed to merge labels")
+		return
+	}
+
+	resp := newResponse(label)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,55 @@
+-- name: List :many
+SELECT * FROM labels
+WHERE COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0)
+ORDER BY lower(name) ASC, id ASC;
+
+-- name: GetByID :one
+SELECT * FROM labels WHERE id = $1;
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM labels WHERE id = $1 FOR UPDATE;
+
+-- name: NameTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM labels
+    WHERE COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0)
+      AND lower(name) = lower(sqlc.arg(name)) AND id <> sqlc.arg(exclude_id)
+) AS exists;
+
+-- name: Create :one
+INSERT INTO labels (workspace_id, owner_id, name, color)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: Update :one
+UPDATE labels SET name = $2, color = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM labels WHERE id = $1;
+
+-- name: MoveTasks :exec
+INSERT INTO task_labels (task_id, label_id)
+SELECT task_id, sqlc.arg(to_label_id) FROM task_labels WHERE label_id = sqlc.arg(from_label_id)
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+-- name: BumpTaskVersions :exec
+UPDATE tasks SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (SELECT task_id FROM task_labels WHERE label_id = $1);
+
+-- name: ListLabeledTasks :many
+SELECT task_id FROM task_labels WHERE label_id = $1 ORDER BY task_id ASC;
+
+-- name: ListTaskLabelNames :many
+-- Names are in the order tasks render them. Tasks without labels are left out.
+SELECT tl.task_id, array_agg(l.name ORDER BY lower(l.name) ASC)::text[] AS names
+FROM task_labels tl
+JOIN labels l ON l.id = tl.label_id
+WHERE tl.task_id = ANY(sqlc.arg(task_ids)::int[])
+GROUP BY tl.task_id;
+
+-- name: CreateTaskEvent :exec
+INSERT INTO task_events (task_id, actor_id, action, changes)
+VALUES ($1, $2, 'UPDATE', $3);
+
+-- name: GetWorkspaceRole :one
+SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS labels (
+    id SERIAL NOT NULL PRIMARY KEY,
+    -- Labels outside workspaces are shared, like the tasks they belong to
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#808080',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Names are unique per workspace regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS labels_workspace_name_idx ON labels (COALESCE(workspace_id, 0), lower(name));
+
+CREATE TABLE IF NOT EXISTS task_labels (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
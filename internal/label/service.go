@@ -0,0 +1,412 @@
+package label
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"slices"
+)
+
+var (
+	ErrNotFound = errors.New("label not found")
+	// ErrWorkspaceNotFound is returned when listing or creating labels in a workspace the
+	// actor is not a member of
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrForbidden         = errors.New("insufficient workspace role")
+	ErrNameTaken         = errors.New("label name already exists")
+	// ErrInvalidMerge is returned when merging a label into itself or into a label of
+	// another workspace
+	ErrInvalidMerge = errors.New("labels cannot be merged")
+)
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+// List returns the labels of a workspace, or the shared labels when workspaceID is nil.
+func (s Service) List(ctx context.Context, viewerID uuid.UUID, workspaceID *int32) ([]Label, error) {
+	workspaceParam := pgtype.Int4{}
+	if workspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *workspaceID, Valid: true}
+	}
+
+	err := checkWorkspaceRole(ctx, s.queries, workspaceParam, viewerID, false)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrWorkspaceNotFound
+		}
+		s.logger.Error("Failed to check workspace role", zap.Error(err))
+		return nil, err
+	}
+
+	labels, err := s.queries.List(ctx, workspaceParam)
+	if err != nil {
+		s.logger.Error("Failed to list labels", zap.Error(err))
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (s Service) GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (Label, error) {
+	label, err := s.queries.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Label{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get label by ID", zap.Error(err))
+		return Label{}, err
+	}
+
+	err = checkWorkspaceRole(ctx, s.queries, label.WorkspaceID, viewerID, false)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			s.logger.Error("Failed to check workspace role", zap.Error(err))
+		}
+		return Label{}, err
+	}
+	return label, nil
+}
+
+func (s Service) Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, name, color string) (Label, error) {
+	workspaceParam := pgtype.Int4{}
+	if workspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *workspaceID, Valid: true}
+	}
+
+	var label Label
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := checkWorkspaceRole(ctx, q, workspaceParam, actorID, true)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrWorkspaceNotFound
+			}
+			return err
+		}
+
+		err = checkNameFree(ctx, q, workspaceParam, name, 0)
+		if err != nil {
+			return err
+		}
+
+		label, err = q.Create(ctx, CreateParams{
+			WorkspaceID: workspaceParam,
+			OwnerID:     pgtype.UUID{Bytes: actorID, Valid: true},
+			Name:        name,
+			Color:       color,
+		})
+		return nameTakenError(err)
+	})
+	if err != nil {
+		s.logWriteError("Failed to create label", err)
+		return Label{}, err
+	}
+
+	s.logger.Info("Created label", zap.Int32("label_id", label.ID), zap.String("owner_id", actorID.String()))
+	return label, nil
+}
+
+// Update renames or recolors the label. A rename shows up on every task with the label,
+// so their versions change as well and it is recorded in their history.
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, id int32, name, color string) (Label, error) {
+	var label Label
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		current, err := lockForWrite(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+
+		err = checkNameFree(ctx, q, current.WorkspaceID, name, id)
+		if err != nil {
+			return err
+		}
+
+		// A snapshot without tasks records nothing
+		var snapshot labelSnapshot
+		if name != current.Name {
+			snapshot, err = snapshotLabels(ctx, q, id)
+			if err != nil {
+				return err
+			}
+
+			err = q.BumpTaskVersions(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		label, err = q.Update(ctx, UpdateParams{
+			ID:    id,
+			Name:  name,
+			Color: color,
+		})
+		if err != nil {
+			return nameTakenError(err)
+		}
+
+		return snapshot.recordChanges(ctx, q, actorID)
+	})
+	if err != nil {
+		s.logWriteError("Failed to update label", err)
+		return Label{}, err
+	}
+	return label, nil
+}
+
+// Delete removes the label from every task, recording it in their history, and deletes it.
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForWrite(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := snapshotLabels(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.BumpTaskVersions(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return snapshot.recordChanges(ctx, q, actorID)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete label", err)
+		return err
+	}
+
+	s.logger.Info("Deleted label", zap.Int32("label_id", id))
+	return nil
+}
+
+// Merge moves every task from the label to intoID and deletes the label, recording the
+// change in the history of the tasks. Both labels must belong to the same workspace.
+func (s Service) Merge(ctx context.Context, actorID uuid.UUID, id, intoID int32) (Label, error) {
+	if id == intoID {
+		return Label{}, ErrInvalidMerge
+	}
+
+	var into Label
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		// Lock in ID order so that two opposite merges cannot deadlock
+		first, second := id, intoID
+		if first > second {
+			first, second = second, first
+		}
+		locked := make(map[int32]Label, 2)
+		for _, labelID := range []int32{first, second} {
+			label, err := lockForWrite(ctx, q, actorID, labelID)
+			if err != nil {
+				return err
+			}
+			locked[labelID] = label
+		}
+
+		into = locked[intoID]
+		if locked[id].WorkspaceID != into.WorkspaceID {
+			return ErrInvalidMerge
+		}
+
+		snapshot, err := snapshotLabels(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.BumpTaskVersions(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.MoveTasks(ctx, MoveTasksParams{
+			FromLabelID: id,
+			ToLabelID:   intoID,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return snapshot.recordChanges(ctx, q, actorID)
+	})
+	if err != nil {
+		s.logWriteError("Failed to merge labels", err)
+		return Label{}, err
+	}
+
+	s.logger.Info("Merged labels", zap.Int32("label_id", id), zap.Int32("into_label_id", intoID))
+	return into, nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err,
+		ErrNotFound, ErrWorkspaceNotFound, ErrForbidden, ErrNameTaken, ErrInvalidMerge)
+}
+
+// lockForWrite locks the label for the rest of the transaction and checks that the actor
+// may change it.
+func lockForWrite(ctx context.Context, q *Queries, actorID uuid.UUID, id int32) (Label, error) {
+	label, err := q.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Label{}, ErrNotFound
+		}
+		return Label{}, err
+	}
+
+	err = checkWorkspaceRole(ctx, q, label.WorkspaceID, actorID, true)
+	if err != nil {
+		return Label{}, err
+	}
+	return label, nil
+}
+
+// checkWorkspaceRole makes sure the user is a member of the workspace, and not a viewer
+// when write is set. Shared labels can be managed by everyone.
+func checkWorkspaceRole(ctx context.Context, q *Queries, workspaceID pgtype.Int4, userID uuid.UUID, write bool) error {
+	if !workspaceID.Valid {
+		return nil
+	}
+
+	role, err := q.GetWorkspaceRole(ctx, GetWorkspaceRoleParams{
+		WorkspaceID: workspaceID.Int32,
+		UserID:      userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Hide the labels from non-members altogether
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if write && role == WorkspaceRoleVIEWER {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// nameTakenError maps the unique index on label names, hit when a concurrent request takes
+// the name after checkNameFree, to ErrNameTaken.
+func nameTakenError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == "labels_workspace_name_idx" {
+		return ErrNameTaken
+	}
+	return err
+}
+
+func checkNameFree(ctx context.Context, q *Queries, workspaceID pgtype.Int4, name string, excludeID int32) error {
+	taken, err := q.NameTaken(ctx, NameTakenParams{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		ExcludeID:   excludeID,
+	})
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrNameTaken
+	}
+	return nil
+}
+
+// labelSnapshot holds the label names of the tasks that carry a label, so that their
+// history can show how a change to the label affected them.
+type labelSnapshot struct {
+	taskIDs []int32
+	names   map[int32][]string
+}
+
+func snapshotLabels(ctx context.Context, q *Queries, labelID int32) (labelSnapshot, error) {
+	taskIDs, err := q.ListLabeledTasks(ctx, labelID)
+	if err != nil {
+		return labelSnapshot{}, err
+	}
+
+	names, err := taskLabelNames(ctx, q, taskIDs)
+	if err != nil {
+		return labelSnapshot{}, err
+	}
+	return labelSnapshot{taskIDs: taskIDs, names: names}, nil
+}
+
+// recordChanges adds an update to the history of every task whose labels no longer match
+// the snapshot, in the form the task package records label changes.
+func (snapshot labelSnapshot) recordChanges(ctx context.Context, q *Queries, actorID uuid.UUID) error {
+	if len(snapshot.taskIDs) == 0 {
+		return nil
+	}
+
+	after, err := taskLabelNames(ctx, q, snapshot.taskIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, taskID := range snapshot.taskIDs {
+		from, to := snapshot.names[taskID], after[taskID]
+		if slices.Equal(from, to) {
+			continue
+		}
+		if to == nil {
+			to = []string{}
+		}
+
+		changes, err := json.Marshal(map[string]map[string][]string{
+			"labels": {"from": from, "to": to},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.CreateTaskEvent(ctx, CreateTaskEventParams{
+			TaskID:  taskID,
+			ActorID: actorID,
+			Changes: changes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func taskLabelNames(ctx context.Context, q *Queries, taskIDs []int32) (map[int32][]string, error) {
+	rows, err := q.ListTaskLabelNames(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int32][]string, len(rows))
+	for _, row := range rows {
+		names[row.TaskID] = row.Names
+	}
+	return names, nil
+}
@@ -0,0 +1,18 @@
+package internal
+
+import (
+	"errors"
+	"go.uber.org/zap"
+)
+
+// LogWriteError logs a failed write. Errors matching one of expected are caused by the
+// request rather than by the server, so they are logged at info level.
+func LogWriteError(logger *zap.Logger, message string, err error, expected ...error) {
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			logger.Info(message, zap.Error(err))
+			return
+		}
+	}
+	logger.Error(message, zap.Error(err))
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+)
+
+// Optional holds a JSON member that may be absent, explicitly null, or set to a value.
+// The zero value means the member was absent from the document.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+	Null  bool
+}
+
+// UnmarshalJSON rejects unknown members of object values like ParseRequestBody does, since
+// the decoder's DisallowUnknownFields does not reach custom unmarshalers.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		o.Null = true
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(&o.Value)
+}
+
+// HasValue reports whether the member was present with a non-null value.
+func (o Optional[T]) HasValue() bool {
+	return o.Set && !o.Null
+}
+
+// ValidateOptional is meant to be called from a struct-level validation. It reports a
+// "notnull" error when a non-nullable member is null, and otherwise checks a present
+// value against tag, e.g. "required,oneof=A B".
+func ValidateOptional[T any](sl validator.StructLevel, o Optional[T], fieldName, structFieldName string, nullable bool, tag string) {
+	if !o.Set {
+		return
+	}
+
+	if o.Null {
+		if !nullable {
+			sl.ReportError(nil, fieldName, structFieldName, "notnull", "")
+		}
+		return
+	}
+
+	if tag == "" {
+		return
+	}
+
+	err := sl.Validator().Var(o.Value, tag)
+	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok && len(validationErrors) > 0 {
+			sl.ReportError(o.Value, fieldName, structFieldName, validationErrors[0].Tag(), validationErrors[0].Param())
+		}
+	}
+}
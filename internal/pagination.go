@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var ErrInvalidPagination = errors.New("invalid pagination parameters")
+
+type Pagination struct {
+	Limit  int32
+	Offset int32
+}
+
+// ParsePagination reads the limit and offset query parameters, falling back to
+// DefaultPageLimit and clamping the limit to MaxPageLimit.
+func ParsePagination(r *http.Request) (Pagination, error) {
+	page := Pagination{Limit: DefaultPageLimit}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || limit <= 0 {
+			return Pagination{}, ErrInvalidPagination
+		}
+		page.Limit = int32(min(limit, MaxPageLimit))
+	}
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || offset < 0 {
+			return Pagination{}, ErrInvalidPagination
+		}
+		page.Offset = int32(offset)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,439 @@
+package project
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultColor = "#808080"
+
+type Response struct {
+	ID        int32     `json:"id"`
+	OwnerID   string    `json:"ownerId"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CreateRequest struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Color    string `json:"color" validate:"required,hexcolor"`
+	Archived bool   `json:"archived"`
+}
+
+type StatusResponse struct {
+	Key      string         `json:"key"`
+	Name     string         `json:"name"`
+	Category StatusCategory `json:"category"`
+	Position int32          `json:"position"`
+}
+
+type TransitionResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type WorkflowResponse struct {
+	Statuses    []StatusResponse     `json:"statuses"`
+	Transitions []TransitionResponse `json:"transitions"`
+}
+
+type StatusRequest struct {
+	Key      string         `json:"key" validate:"required,max=50,uppercase,excludesall= "`
+	Name     string         `json:"name" validate:"required,max=100"`
+	Category StatusCategory `json:"category" validate:"required,oneof=TODO DOING DONE"`
+}
+
+type TransitionRequest struct {
+	From string `json:"from" validate:"required"`
+	To   string `json:"to" validate:"required"`
+}
+
+// WorkflowRequest replaces the workflow of a project. Statuses are listed in board order,
+// and leaving out the transitions allows every transition.
+type WorkflowRequest struct {
+	Statuses    []StatusRequest     `json:"statuses" validate:"required,min=1,max=50,dive"`
+	Transitions []TransitionRequest `json:"transitions" validate:"omitempty,dive"`
+}
+
+type Store interface {
+	GetAll(ctx context.Context, ownerID uuid.UUID, includeArchived bool) ([]Project, error)
+	GetByID(ctx context.Context, ownerID uuid.UUID, id int32) (Project, error)
+	Create(ctx context.Context, ownerID uuid.UUID, name, color string) (Project, error)
+	Update(ctx context.Context, ownerID uuid.UUID, id int32, name, color string, archived bool) (Project, error)
+	Delete(ctx context.Context, ownerID uuid.UUID, id int32) error
+	GetWorkflow(ctx context.Context, ownerID uuid.UUID, id int32) (Workflow, error)
+	SetWorkflow(ctx context.Context, ownerID uuid.UUID, id int32, statuses []Status, transitions []Transition) (Workflow, error)
+}
+
+type taskStore interface {
+	GetAll(ctx context.Context, viewerID uuid.UUID, filter task.Filter) ([]task.Task, error)
+	GetDetails(ctx context.Context, taskIDs ...int32) (map[int32]task.Details, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+	taskStore taskStore
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store, taskStore taskStore) *Handler {
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+		taskStore: taskStore,
+	}
+}
+
+func newResponse(project Project) Response {
+	return Response{
+		ID:        project.ID,
+		OwnerID:   project.OwnerID.String(),
+		Name:      project.Name,
+		Color:     project.Color,
+		Archived:  project.Archived,
+		CreatedAt: project.CreatedAt.Time,
+		UpdatedAt: project.UpdatedAt.Time,
+	}
+}
+
+func newWorkflowResponse(workflow Workflow) WorkflowResponse {
+	resp := WorkflowResponse{
+		Statuses:    make([]StatusResponse, len(workflow.Statuses)),
+		Transitions: make([]TransitionResponse, len(workflow.Transitions)),
+	}
+
+	keys := make(map[int32]string, len(workflow.Statuses))
+	for i, status := range workflow.Statuses {
+		keys[status.ID] = status.Key
+		resp.Statuses[i] = StatusResponse{
+			Key:      status.Key,
+			Name:     status.Name,
+			Category: status.Category,
+			Position: status.Position,
+		}
+	}
+	for i, transition := range workflow.Transitions {
+		resp.Transitions[i] = TransitionResponse{
+			From: keys[transition.FromStatusID],
+			To:   keys[transition.ToStatusID],
+		}
+	}
+	return resp
+}
+
+// writeStoreError maps errors returned by the store to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Project not found", http.StatusNotFound)
+	case errors.Is(err, ErrInvalidWorkflow):
+		http.Error(w, "Workflow needs unique status keys, a TODO and a DONE status, and transitions between its own statuses", http.StatusBadRequest)
+	case errors.Is(err, ErrStatusInUse):
+		http.Error(w, "Cannot remove a status that tasks still have", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+	includeArchived := r.URL.Query().Get("includeArchived") == "true"
+
+	projects, err := h.store.GetAll(ctx, userID, includeArchived)
+	if err != nil {
+		h.logger.Error("Failed to get projects", zap.Error(err))
+		http.Error(w, "Failed to get projects", http.StatusInternalServerError)
+		return
+	}
+
+	var resp = make([]Response, len(projects))
+	for i, project := range projects {
+		resp[i] = newResponse(project)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	project, err := h.store.GetByID(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to get project by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get project")
+		return
+	}
+
+	resp := newResponse(project)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	if req.Color == "" {
+		req.Color = DefaultColor
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	newProject, err := h.store.Create(ctx, userID, req.Name, req.Color)
+	if err != nil {
+		h.logger.Error("Failed to create project", zap.Error(err))
+		http.Error(w, "Failed to create project", http.StatusInternalServerError)
+		return
+	}
+
+	resp := newResponse(newProject)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateRequest
+	err = internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	updatedProject, err := h.store.Update(ctx, userID, int32(id), req.Name, req.Color, req.Archived)
+	if err != nil {
+		h.logger.Error("Failed to update project", zap.Error(err))
+		writeStoreError(w, err, "Failed to update project")
+		return
+	}
+
+	resp := newResponse(updatedProject)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.Delete(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to delete project", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete project")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTasks lists the tasks of a project, accepting the same filters as GET /api/task.
+func (h *Handler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	// Make sure the project belongs to the caller before listing its tasks
+	project, err := h.store.GetByID(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to get project by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get project")
+		return
+	}
+
+	filter, err := task.ParseFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid task filter", http.StatusBadRequest)
+		return
+	}
+	filter.ProjectID = &project.ID
+
+	tasks, err := h.taskStore.GetAll(ctx, userID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get project tasks", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int32, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	details, err := h.taskStore.GetDetails(ctx, ids...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	resp := task.NewResponses(tasks, details)
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	workflow, err := h.store.GetWorkflow(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to get project workflow", zap.Error(err))
+		writeStoreError(w, err, "Failed to get workflow")
+		return
+	}
+
+	resp := newWorkflowResponse(workflow)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) SetWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract project ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Project ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req WorkflowRequest
+	err = internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	statuses := make([]Status, len(req.Statuses))
+	for i, status := range req.Statuses {
+		statuses[i] = Status{
+			Key:      status.Key,
+			Name:     status.Name,
+			Category: status.Category,
+		}
+	}
+	transitions := make([]Transition, len(req.Transitions))
+	for i, transition := range req.Transitions {
+		transitions[i] = Transition{
+			From: transition.From,
+			To:   transition.To,
+		}
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	workflow, err := h.store.SetWorkflow(ctx, userID, int32(id), statuses, transitions)
+	if err != nil {
+		h.logger.Error("Failed to set project workflow", zap.Error(err))
+		writeStoreError(w, err, "Failed to update workflow")
+		return
+	}
+
+	resp := newWorkflowResponse(workflow)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,103 @@
+-- name: GetAllByOwner :many
+SELECT * FROM projects
+WHERE owner_id = sqlc.arg(owner_id) AND (sqlc.arg(include_archived)::bool OR NOT archived)
+ORDER BY id ASC;
+
+-- name: GetByID :one
+SELECT * FROM projects WHERE id = $1 AND owner_id = $2;
+
+-- name: Create :one
+INSERT INTO projects (owner_id, name, color)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: Update :one
+UPDATE projects
+SET name = $3, color = $4, archived = $5, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND owner_id = $2
+RETURNING *;
+
+-- name: Delete :execrows
+DELETE FROM projects WHERE id = $1 AND owner_id = $2;
+
+-- name: CopyDefaultStatuses :exec
+INSERT INTO task_statuses (project_id, key, name, category, position)
+SELECT sqlc.arg(project_id), key, name, category, position
+FROM task_statuses
+WHERE project_id IS NULL;
+
+-- name: ResetTaskStatuses :exec
+-- Takes the tasks out of a project that is about to be deleted, moving them to the default
+-- status of their status category, and records the change in their history. The joined
+-- copy of tasks still holds the status from before the update.
+WITH reset AS (
+    UPDATE tasks t
+    SET project_id = NULL,
+        status = (
+            SELECT s.key FROM task_statuses s
+            WHERE s.project_id IS NULL AND s.category = t.status_category
+            ORDER BY s.position ASC, s.id ASC
+            LIMIT 1
+        ),
+        version = t.version + 1, updated_at = CURRENT_TIMESTAMP
+    FROM tasks old
+    WHERE old.id = t.id AND t.project_id = sqlc.arg(project_id)::int
+    RETURNING t.id, old.status AS old_status, t.status
+)
+INSERT INTO task_events (task_id, actor_id, action, changes)
+SELECT id, sqlc.arg(actor_id)::uuid, 'UPDATE',
+       jsonb_build_object('projectId', jsonb_build_object('from', sqlc.arg(project_id)::int, 'to', NULL::int))
+       || CASE WHEN status <> old_status
+               THEN jsonb_build_object('status', jsonb_build_object('from', old_status, 'to', status))
+               ELSE '{}'::jsonb END
+FROM reset;
+
+-- name: ListStatuses :many
+SELECT * FROM task_statuses WHERE project_id = $1 ORDER BY position ASC, id ASC;
+
+-- name: ListTransitions :many
+SELECT tr.* FROM task_status_transitions tr
+JOIN task_statuses s ON s.id = tr.from_status_id
+WHERE s.project_id = $1;
+
+-- name: UpsertStatus :one
+INSERT INTO task_statuses (project_id, key, name, category, position)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (COALESCE(project_id, 0), key) DO UPDATE
+SET name = EXCLUDED.name, category = EXCLUDED.category, position = EXCLUDED.position
+RETURNING *;
+
+-- name: CountTasksOutsideStatuses :one
+SELECT count(*) FROM tasks WHERE project_id = sqlc.arg(project_id) AND NOT (status = ANY(sqlc.arg(keys)::text[]));
+
+-- name: DeleteStatusesExcept :exec
+DELETE FROM task_statuses WHERE project_id = sqlc.arg(project_id) AND NOT (key = ANY(sqlc.arg(keys)::text[]));
+
+-- name: ClearTransitions :exec
+DELETE FROM task_status_transitions
+WHERE from_status_id IN (SELECT id FROM task_statuses WHERE project_id = $1);
+
+-- name: AddTransition :exec
+INSERT INTO task_status_transitions (from_status_id, to_status_id)
+VALUES ($1, $2)
+ON CONFLICT (from_status_id, to_status_id) DO NOTHING;
+
+-- name: SyncTaskCategories :exec
+-- The parents of the synced tasks are bumped too, since they show how many subtasks are done,
+-- and so are the tasks they block. Tasks synced themselves are left out: a row cannot be
+-- updated twice in one statement.
+WITH synced AS (
+    UPDATE tasks t
+    SET status_category = s.category, version = t.version + 1, updated_at = CURRENT_TIMESTAMP
+    FROM task_statuses s
+    WHERE t.project_id = sqlc.arg(project_id) AND s.project_id = sqlc.arg(project_id)
+      AND s.key = t.status AND t.status_category <> s.category
+    RETURNING t.id, t.parent_id
+)
+UPDATE tasks SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE (id IN (SELECT parent_id FROM synced)
+       OR id IN (SELECT d.task_id FROM task_dependencies d JOIN synced ON synced.id = d.blocked_by_id))
+  AND id NOT IN (SELECT id FROM synced);
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM projects WHERE id = $1 AND owner_id = $2 FOR UPDATE;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL NOT NULL PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#808080',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS projects_owner_id_idx ON projects (owner_id);
+
+CREATE TYPE status_category AS ENUM ('TODO', 'DOING', 'DONE');
+
+CREATE TABLE IF NOT EXISTS task_statuses (
+    id SERIAL NOT NULL PRIMARY KEY,
+    -- Statuses without a project make up the default workflow, which is copied into new projects
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    category status_category NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS task_statuses_project_key_idx ON task_statuses (COALESCE(project_id, 0), key);
+
+-- A workflow without transitions allows moving between any of its statuses
+CREATE TABLE IF NOT EXISTS task_status_transitions (
+    from_status_id INTEGER NOT NULL REFERENCES task_statuses(id) ON DELETE CASCADE,
+    to_status_id INTEGER NOT NULL REFERENCES task_statuses(id) ON DELETE CASCADE,
+    PRIMARY KEY (from_status_id, to_status_id)
+);
@@ -0,0 +1,149 @@
+package project
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrNotFound = errors.New("project not found")
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+func (s Service) GetAll(ctx context.Context, ownerID uuid.UUID, includeArchived bool) ([]Project, error) {
+	projects, err := s.queries.GetAllByOwner(ctx, GetAllByOwnerParams{
+		OwnerID:         ownerID,
+		IncludeArchived: includeArchived,
+	})
+	if err != nil {
+		s.logger.Error("Failed to get projects", zap.Error(err))
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (s Service) GetByID(ctx context.Context, ownerID uuid.UUID, id int32) (Project, error) {
+	project, err := s.queries.GetByID(ctx, GetByIDParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Project{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get project by ID", zap.Error(err))
+		return Project{}, err
+	}
+	return project, nil
+}
+
+// Create adds a project that starts out with a copy of the default workflow.
+func (s Service) Create(ctx context.Context, ownerID uuid.UUID, name, color string) (Project, error) {
+	var project Project
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		project, err = q.Create(ctx, CreateParams{
+			OwnerID: ownerID,
+			Name:    name,
+			Color:   color,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.CopyDefaultStatuses(ctx, pgtype.Int4{Int32: project.ID, Valid: true})
+	})
+	if err != nil {
+		s.logger.Error("Failed to create project", zap.Error(err))
+		return Project{}, err
+	}
+
+	s.logger.Info("Created project", zap.Int32("project_id", project.ID), zap.String("owner_id", ownerID.String()))
+	return project, nil
+}
+
+func (s Service) Update(ctx context.Context, ownerID uuid.UUID, id int32, name, color string, archived bool) (Project, error) {
+	project, err := s.queries.Update(ctx, UpdateParams{
+		ID:       id,
+		OwnerID:  ownerID,
+		Name:     name,
+		Color:    color,
+		Archived: archived,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Project{}, ErrNotFound
+		}
+		s.logger.Error("Failed to update project", zap.Error(err))
+		return Project{}, err
+	}
+	return project, nil
+}
+
+// Delete removes the project. Its tasks are kept and become unassigned, moving to the
+// default status of their status category, which shows in their history as a change
+// made by the owner.
+func (s Service) Delete(ctx context.Context, ownerID uuid.UUID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, ownerID, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.ResetTaskStatuses(ctx, ResetTaskStatusesParams{
+			ProjectID: id,
+			ActorID:   ownerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Delete(ctx, DeleteParams{
+			ID:      id,
+			OwnerID: ownerID,
+		})
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			s.logger.Error("Failed to delete project", zap.Error(err))
+		}
+		return err
+	}
+
+	s.logger.Info("Deleted project", zap.Int32("project_id", id), zap.String("owner_id", ownerID.String()))
+	return nil
+}
+
+// lockForOwner locks the project for the rest of the transaction, returning ErrNotFound
+// unless ownerID owns it.
+func lockForOwner(ctx context.Context, q *Queries, ownerID uuid.UUID, id int32) (Project, error) {
+	project, err := q.GetByIDForUpdate(ctx, GetByIDForUpdateParams{
+		ID:      id,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Project{}, ErrNotFound
+		}
+		return Project{}, err
+	}
+	return project, nil
+}
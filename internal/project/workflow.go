@@ -0,0 +1,189 @@
+package project
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidWorkflow is returned for duplicate status keys, transitions between unknown
+	// statuses, or a workflow without an open and a done status
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+	// ErrStatusInUse is returned when removing a status that tasks of the project still have
+	ErrStatusInUse = errors.New("status is still used by tasks")
+)
+
+// Status is a status of a workflow as passed to SetWorkflow.
+type Status struct {
+	Key      string
+	Name     string
+	Category StatusCategory
+}
+
+// Transition allows moving a task from one status to another, by status key.
+type Transition struct {
+	From string
+	To   string
+}
+
+// Workflow holds the statuses of a project in order and the transitions allowed between
+// them. A workflow without transitions allows every transition.
+type Workflow struct {
+	Statuses    []TaskStatus
+	Transitions []TaskStatusTransition
+}
+
+func (s Service) GetWorkflow(ctx context.Context, ownerID uuid.UUID, id int32) (Workflow, error) {
+	_, err := s.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	workflow, err := getWorkflow(ctx, s.queries, id)
+	if err != nil {
+		s.logger.Error("Failed to get project workflow", zap.Error(err))
+		return Workflow{}, err
+	}
+	return workflow, nil
+}
+
+// SetWorkflow replaces the workflow of the project. Statuses are matched by key, so tasks
+// keep their status as long as its key is still part of the workflow.
+func (s Service) SetWorkflow(ctx context.Context, ownerID uuid.UUID, id int32, statuses []Status, transitions []Transition) (Workflow, error) {
+	err := validateWorkflow(statuses, transitions)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	var workflow Workflow
+	err = internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, ownerID, id)
+		if err != nil {
+			return err
+		}
+
+		projectID := pgtype.Int4{Int32: id, Valid: true}
+		keys := make([]string, len(statuses))
+		for i, status := range statuses {
+			keys[i] = status.Key
+		}
+
+		// Trashed tasks count as well, since they keep their status when restored
+		inUse, err := q.CountTasksOutsideStatuses(ctx, CountTasksOutsideStatusesParams{
+			ProjectID: projectID,
+			Keys:      keys,
+		})
+		if err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrStatusInUse
+		}
+
+		err = q.ClearTransitions(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteStatusesExcept(ctx, DeleteStatusesExceptParams{
+			ProjectID: projectID,
+			Keys:      keys,
+		})
+		if err != nil {
+			return err
+		}
+
+		statusIDs := make(map[string]int32, len(statuses))
+		for i, status := range statuses {
+			saved, err := q.UpsertStatus(ctx, UpsertStatusParams{
+				ProjectID: projectID,
+				Key:       status.Key,
+				Name:      status.Name,
+				Category:  status.Category,
+				Position:  int32(i),
+			})
+			if err != nil {
+				return err
+			}
+			statusIDs[status.Key] = saved.ID
+		}
+
+		for _, transition := range transitions {
+			err = q.AddTransition(ctx, AddTransitionParams{
+				FromStatusID: statusIDs[transition.From],
+				ToStatusID:   statusIDs[transition.To],
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// A status that changed category changes what counts as done for its tasks
+		err = q.SyncTaskCategories(ctx, projectID)
+		if err != nil {
+			return err
+		}
+
+		workflow, err = getWorkflow(ctx, q, id)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrStatusInUse) {
+			s.logger.Info("Failed to set project workflow", zap.Error(err))
+		} else {
+			s.logger.Error("Failed to set project workflow", zap.Error(err))
+		}
+		return Workflow{}, err
+	}
+
+	s.logger.Info("Updated project workflow", zap.Int32("project_id", id), zap.Int("statuses", len(statuses)))
+	return workflow, nil
+}
+
+func validateWorkflow(statuses []Status, transitions []Transition) error {
+	keys := make(map[string]bool, len(statuses))
+	categories := make(map[StatusCategory]bool)
+	for _, status := range statuses {
+		if keys[status.Key] {
+			return ErrInvalidWorkflow
+		}
+		keys[status.Key] = true
+		categories[status.Category] = true
+	}
+
+	// New occurrences of recurring tasks start in an open status and auto-completed
+	// parents move to a done status
+	if !categories[StatusCategoryTODO] || !categories[StatusCategoryDONE] {
+		return ErrInvalidWorkflow
+	}
+
+	for _, transition := range transitions {
+		if !keys[transition.From] || !keys[transition.To] || transition.From == transition.To {
+			return ErrInvalidWorkflow
+		}
+	}
+	return nil
+}
+
+func getWorkflow(ctx context.Context, q *Queries, id int32) (Workflow, error) {
+	projectID := pgtype.Int4{Int32: id, Valid: true}
+
+	statuses, err := q.ListStatuses(ctx, projectID)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	transitions, err := q.ListTransitions(ctx, projectID)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	return Workflow{
+		Statuses:    statuses,
+		Transitions: transitions,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"context"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxQueryLength bounds the search text in characters.
+const MaxQueryLength = 200
+
+// The search query marks matches in snippets with these private use characters, which
+// survive HTML escaping unlike the <mark> tags they are replaced with.
+const (
+	matchStart = "\ue000"
+	matchEnd   = "\ue001"
+)
+
+var highlighter = strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>")
+
+type ResultResponse struct {
+	Type      string    `json:"type"`
+	TaskID    int32     `json:"taskId"`
+	CommentID *int32    `json:"commentId,omitempty"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float32   `json:"rank"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Response struct {
+	Results []ResultResponse `json:"results"`
+	Total   int64            `json:"total"`
+	Limit   int32            `json:"limit"`
+	Offset  int32            `json:"offset"`
+}
+
+type Store interface {
+	Search(ctx context.Context, viewerID uuid.UUID, query Query, page internal.Pagination) ([]SearchRow, int64, error)
+}
+
+type Handler struct {
+	logger *zap.Logger
+	store  Store
+}
+
+func NewHandler(logger *zap.Logger, store Store) *Handler {
+	return &Handler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// highlight escapes the snippet and wraps its matches in <mark> tags.
+func highlight(snippet string) string {
+	return highlighter.Replace(html.EscapeString(snippet))
+}
+
+// parseQuery reads ?q= along with the optional type, workspaceId and projectId filters.
+func parseQuery(r *http.Request) (Query, bool) {
+	values := r.URL.Query()
+
+	query := Query{
+		Text:            strings.TrimSpace(values.Get("q")),
+		IncludeTasks:    true,
+		IncludeComments: true,
+	}
+	if query.Text == "" || len([]rune(query.Text)) > MaxQueryLength {
+		return Query{}, false
+	}
+
+	switch values.Get("type") {
+	case "":
+	case "task":
+		query.IncludeComments = false
+	case "comment":
+		query.IncludeTasks = false
+	default:
+		return Query{}, false
+	}
+
+	if value := values.Get("workspaceId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || id <= 0 {
+			return Query{}, false
+		}
+		query.WorkspaceID = new(int32)
+		*query.WorkspaceID = int32(id)
+	}
+
+	if value := values.Get("projectId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || id <= 0 {
+			return Query{}, false
+		}
+		query.ProjectID = new(int32)
+		*query.ProjectID = int32(id)
+	}
+
+	return query, true
+}
+
+// Search runs a full-text search over the tasks the caller can see and their comments.
+// Anonymous callers only find tasks outside workspaces.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query, ok := parseQuery(r)
+	if !ok {
+		http.Error(w, "Invalid search query", http.StatusBadRequest)
+		return
+	}
+
+	page, err := internal.ParsePagination(r)
+	if err != nil {
+		http.Error(w, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	results, total, err := h.store.Search(ctx, userID, query, page)
+	if err != nil {
+		h.logger.Error("Failed to search", zap.Error(err))
+		http.Error(w, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{
+		Results: make([]ResultResponse, len(results)),
+		Total:   total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+	}
+	for i, result := range results {
+		resp.Results[i] = ResultResponse{
+			Type:      strings.ToLower(result.Kind),
+			TaskID:    result.TaskID,
+			Title:     result.Title,
+			Snippet:   highlight(result.Snippet),
+			Rank:      result.Rank,
+			UpdatedAt: result.UpdatedAt.Time,
+		}
+		if result.CommentID.Valid {
+			resp.Results[i].CommentID = &result.CommentID.Int32
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
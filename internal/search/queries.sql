@@ -0,0 +1,61 @@
+-- name: Search :many
+-- Tasks match on their title and description, or on a title close to the query; comments
+-- match on their body. Both are limited to tasks the viewer can see. Matches in snippets are
+-- wrapped in U+E000 and U+E001 so that the snippet can be escaped before highlighting.
+SELECT kind, task_id, comment_id, title, snippet, rank::real AS rank, updated_at
+FROM (
+    SELECT 'TASK'::text AS kind, t.id AS task_id, NULL::int AS comment_id, t.title,
+           ts_headline('english', coalesce(nullif(t.description, ''), t.title), websearch_to_tsquery('english', sqlc.arg(query)::text),
+                       'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxFragments=2, MaxWords=20, MinWords=5') AS snippet,
+           ts_rank(t.search_vector, websearch_to_tsquery('english', sqlc.arg(query)::text)) + similarity(t.title, sqlc.arg(query)::text) AS rank,
+           t.updated_at
+    FROM tasks t
+    WHERE sqlc.arg(include_tasks)::bool
+      AND t.deleted_at IS NULL
+      AND (t.workspace_id IS NULL OR t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+      AND (sqlc.narg(workspace_id)::int IS NULL OR t.workspace_id = sqlc.narg(workspace_id))
+      AND (sqlc.narg(project_id)::int IS NULL OR t.project_id = sqlc.narg(project_id))
+      AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+      AND (t.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) OR t.title % sqlc.arg(query)::text)
+    UNION ALL
+    SELECT 'COMMENT'::text, t.id, c.id, t.title,
+           ts_headline('english', c.body, websearch_to_tsquery('english', sqlc.arg(query)::text),
+                       'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxFragments=2, MaxWords=20, MinWords=5'),
+           ts_rank(c.search_vector, websearch_to_tsquery('english', sqlc.arg(query)::text)),
+           c.updated_at
+    FROM task_comments c
+    JOIN tasks t ON t.id = c.task_id
+    WHERE sqlc.arg(include_comments)::bool
+      AND t.deleted_at IS NULL
+      AND (t.workspace_id IS NULL OR t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+      AND (sqlc.narg(workspace_id)::int IS NULL OR t.workspace_id = sqlc.narg(workspace_id))
+      AND (sqlc.narg(project_id)::int IS NULL OR t.project_id = sqlc.narg(project_id))
+      AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+      AND c.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text)
+) hits
+ORDER BY rank DESC, updated_at DESC, task_id DESC, comment_id DESC NULLS FIRST
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountSearch :one
+SELECT (
+    (SELECT count(*)
+     FROM tasks t
+     WHERE sqlc.arg(include_tasks)::bool
+       AND t.deleted_at IS NULL
+       AND (t.workspace_id IS NULL OR t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+       AND (sqlc.narg(workspace_id)::int IS NULL OR t.workspace_id = sqlc.narg(workspace_id))
+       AND (sqlc.narg(project_id)::int IS NULL OR t.project_id = sqlc.narg(project_id))
+       AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+       AND (t.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) OR t.title % sqlc.arg(query)::text))
+    +
+    (SELECT count(*)
+     FROM task_comments c
+     JOIN tasks t ON t.id = c.task_id
+     WHERE sqlc.arg(include_comments)::bool
+       AND t.deleted_at IS NULL
+       AND (t.workspace_id IS NULL OR t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+       AND (sqlc.narg(workspace_id)::int IS NULL OR t.workspace_id = sqlc.narg(workspace_id))
+       AND (sqlc.narg(project_id)::int IS NULL OR t.project_id = sqlc.narg(project_id))
+       AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+       AND c.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text))
+)::bigint AS total;
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Query is a full-text search. Text uses the web search syntax, so quoted phrases, "or"
+// and a leading "-" work as expected.
+type Query struct {
+	Text            string
+	WorkspaceID     *int32
+	ProjectID       *int32
+	IncludeTasks    bool
+	IncludeComments bool
+}
+
+type Service struct {
+	logger  *zap.Logger
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		queries: New(db),
+	}
+}
+
+// Search returns the tasks and comments matching the query that the viewer can see, best
+// matches first, along with the total number of matches.
+func (s Service) Search(ctx context.Context, viewerID uuid.UUID, query Query, page internal.Pagination) ([]SearchRow, int64, error) {
+	workspaceParam := pgtype.Int4{}
+	if query.WorkspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *query.WorkspaceID, Valid: true}
+	}
+	projectParam := pgtype.Int4{}
+	if query.ProjectID != nil {
+		projectParam = pgtype.Int4{Int32: *query.ProjectID, Valid: true}
+	}
+
+	results, err := s.queries.Search(ctx, SearchParams{
+		Query:           query.Text,
+		IncludeTasks:    query.IncludeTasks,
+		ViewerID:        viewerID,
+		WorkspaceID:     workspaceParam,
+		ProjectID:       projectParam,
+		IncludeComments: query.IncludeComments,
+		PageLimit:       page.Limit,
+		PageOffset:      page.Offset,
+	})
+	if err != nil {
+		s.logger.Error("Failed to search", zap.Error(err))
+		return nil, 0, err
+	}
+
+	total, err := s.queries.CountSearch(ctx, CountSearchParams{
+		IncludeTasks:    query.IncludeTasks,
+		ViewerID:        viewerID,
+		WorkspaceID:     workspaceParam,
+		ProjectID:       projectParam,
+		Query:           query.Text,
+		IncludeComments: query.IncludeComments,
+	})
+	if err != nil {
+		s.logger.Error("Failed to count search results", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
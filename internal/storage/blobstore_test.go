@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"tasks/1/report.pdf", true},
+		{"avatars/a.b/128.png", true},
+		{"..data/file", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"tasks/../../secret", false},
+		{"tasks/1/..", false},
+		{"tasks/./1", false},
+		{"tasks//1", false},
+		{"tasks/1/", false},
+		{`tasks\..\secret`, false},
+	}
+
+	for _, tt := range tests {
+		err := validateKey(tt.key)
+		if tt.valid && err != nil {
+			t.Errorf("validateKey(%q) = %v, want nil", tt.key, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("validateKey(%q) = %v, want ErrInvalidKey", tt.key, err)
+		}
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(context.Background(), "../outside", strings.NewReader("x"), 1, "text/plain")
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put = %v, want ErrInvalidKey", err)
+	}
+
+	_, err = store.Get(context.Background(), "tasks/../../outside")
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalStorePutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Put(ctx, "tasks/1/note.txt", strings.NewReader("hello"), 5, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := store.Get(ctx, "tasks/1/note.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	_ = body.Close()
+	if string(content) != "hello" {
+		t.Errorf("got %q, want hello", content)
+	}
+
+	err = store.Delete(ctx, "tasks/1/note.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get(ctx, "tasks/1/note.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
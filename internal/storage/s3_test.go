@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion          = "eu-west-1"
+)
+
+var authorizationPattern = regexp.MustCompile(
+	`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+type fakeObject struct {
+	content     []byte
+	contentType string
+}
+
+// fakeS3 stands in for an S3-compatible service. It checks the Signature Version 4 of
+// every request and keeps objects in memory by path.
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	// authorizations records the Authorization header of every request
+	authorizations []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{t: t, objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.authorizations = append(f.authorizations, r.Header.Get("Authorization"))
+	if err := verifySignature(r); err != nil {
+		f.t.Logf("rejected request: %v", err)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	switch r.Method {
+	case http.MethodPut:
+		content, _ := io.ReadAll(r.Body)
+		f.objects[path] = fakeObject{content: content, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		object, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		_, _ = w.Write(object.content)
+	case http.MethodDelete:
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature recomputes the signature of the request the way S3 does.
+func verifySignature(r *http.Request) error {
+	match := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if match == nil {
+		return fmt.Errorf("malformed authorization %q", r.Header.Get("Authorization"))
+	}
+	accessKeyID, date, region, signedHeaders, signature := match[1], match[2], match[3], match[4], match[5]
+	if accessKeyID != testAccessKeyID || region != testRegion {
+		return fmt.Errorf("unexpected credential %s for %s", accessKeyID, region)
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date) {
+		return fmt.Errorf("x-amz-date %q does not match the credential date %s", amzDate, date)
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		date + "/" + region + "/s3/aws4_request",
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + testSecretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = testHMAC(key, part)
+	}
+	expected := hex.EncodeToString(testHMAC(key, stringToSign))
+	if expected != signature {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+func testHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func newTestS3Store(t *testing.T, endpoint, secret string) *S3Store {
+	t.Helper()
+	store, err := NewS3Store(S3Config{
+		Endpoint:        endpoint,
+		Region:          testRegion,
+		Bucket:          "attachments",
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+
+	// The space and the tilde check the encoding of the signed path
+	key := "tasks/1/my report~v2.pdf"
+	err := store.Put(ctx, key, strings.NewReader("%PDF-1.4"), 8, "application/pdf")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	object, ok := fake.objects["/attachments/tasks/1/my%20report~v2.pdf"]
+	if !ok {
+		t.Fatalf("object not stored under the bucket path, have %v", fake.objects)
+	}
+	if object.contentType != "application/pdf" {
+		t.Errorf("got content type %q, want application/pdf", object.contentType)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	content, _ := io.ReadAll(body)
+	_ = body.Close()
+	if string(content) != "%PDF-1.4" {
+		t.Errorf("got %q, want %%PDF-1.4", content)
+	}
+
+	err = store.Delete(ctx, key)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = store.Get(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+
+	// Deleting a missing blob is not an error
+	err = store.Delete(ctx, key)
+	if err != nil {
+		t.Errorf("second Delete = %v, want nil", err)
+	}
+}
+
+func TestS3StoreGetMissing(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+
+	_, err := store.Get(context.Background(), "tasks/1/missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreAuthorizationHeader(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+
+	err := store.Put(context.Background(), "tasks/1/a.txt", strings.NewReader("a"), 1, "text/plain")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	match := authorizationPattern.FindStringSubmatch(fake.authorizations[0])
+	if match == nil {
+		t.Fatalf("malformed authorization %q", fake.authorizations[0])
+	}
+	if match[1] != testAccessKeyID || match[3] != testRegion {
+		t.Errorf("got credential %s for %s", match[1], match[3])
+	}
+	if match[4] != "host;x-amz-content-sha256;x-amz-date" {
+		t.Errorf("got signed headers %s", match[4])
+	}
+}
+
+func TestS3StoreWrongSecret(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, "not-the-secret")
+
+	err := store.Put(context.Background(), "tasks/1/a.txt", strings.NewReader("a"), 1, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got %v, want a 403 error", err)
+	}
+}
+
+func TestS3StoreRejectsInvalidKey(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3Store(t, server.URL, testSecretAccessKey)
+
+	_, err := store.Get(context.Background(), "../other-bucket/key")
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("got %v, want ErrInvalidKey", err)
+	}
+	if len(fake.authorizations) != 0 {
+		t.Error("an invalid key reached the service")
+	}
+}
@@ -0,0 +1,235 @@
+package task
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// rankDigits are the digits of a rank in ascending byte order, so that ranks compare
+// correctly under the "C" collation.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// ErrInvalidPosition is returned when a neighbor given to Move is not in the target column,
+// or when both neighbors are given and they are not adjacent.
+var ErrInvalidPosition = errors.New("invalid board position")
+
+// Position places a task on the board. AfterID is the task that ends up directly above
+// it and BeforeID the one directly below. Without neighbors the task goes to the bottom
+// of the column.
+type Position struct {
+	Status   string
+	AfterID  *int32
+	BeforeID *int32
+}
+
+// Column is a status of the board with its tasks in rank order.
+type Column struct {
+	Status TaskStatus
+	Tasks  []Task
+}
+
+// Move changes the status and board position of a task. It goes through the same checks
+// as Patch, so the workflow and blockers apply to the new status.
+func (s Service) Move(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, position Position, force bool) (Task, error) {
+	fields := PatchFields{
+		Force:    force,
+		position: &position,
+	}
+	if position.Status != "" {
+		fields.Status = internal.Optional[string]{Set: true, Value: position.Status}
+	}
+	return s.Patch(ctx, actorID, id, expectedVersion, fields)
+}
+
+// GetBoard lists the visible tasks by status. The columns follow the workflow of the
+// filtered project, or the default workflow; statuses from other workflows are added
+// after them.
+func (s Service) GetBoard(ctx context.Context, viewerID uuid.UUID, filter Filter) ([]Column, error) {
+	tasks, err := s.getAll(ctx, viewerID, filter, true)
+	if err != nil {
+		return nil, err
+	}
+
+	projectParam := pgtype.Int4{}
+	if filter.ProjectID != nil {
+		projectParam = pgtype.Int4{Int32: *filter.ProjectID, Valid: true}
+	}
+	statuses, err := s.queries.ListStatuses(ctx, projectParam)
+	if err != nil {
+		s.logger.Error("Failed to list task statuses", zap.Error(err))
+		return nil, err
+	}
+
+	columns := make([]Column, len(statuses))
+	byKey := make(map[string]int, len(statuses))
+	for i, status := range statuses {
+		columns[i] = Column{Status: status, Tasks: []Task{}}
+		byKey[status.Key] = i
+	}
+	for _, task := range tasks {
+		i, ok := byKey[task.Status]
+		if !ok {
+			i = len(columns)
+			byKey[task.Status] = i
+			columns = append(columns, Column{
+				Status: TaskStatus{Key: task.Status, Name: task.Status, Category: task.StatusCategory},
+			})
+		}
+		columns[i].Tasks = append(columns[i].Tasks, task)
+	}
+	return columns, nil
+}
+
+// columnKey identifies a board column. Ranks only order the tasks of the same column.
+type columnKey struct {
+	workspaceID pgtype.Int4
+	projectID   pgtype.Int4
+	status      string
+}
+
+// placeTask returns the rank that puts the task at position in the column given by
+// projectID and status. Only the moved task gets a new rank; a task that changes column
+// some other way keeps its rank, and ties within a column are ordered by ID.
+func placeTask(ctx context.Context, q *Queries, current *Task, projectID pgtype.Int4, status string, position Position) (pgtype.Text, error) {
+	column := columnKey{
+		workspaceID: current.WorkspaceID,
+		projectID:   projectID,
+		status:      status,
+	}
+
+	// Moves within a column are serialized so that two tasks dropped at the same spot get
+	// different ranks
+	err := q.LockRanks(ctx, LockRanksParams{
+		WorkspaceID: column.workspaceID,
+		ProjectID:   column.projectID,
+		Status:      column.status,
+	})
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+
+	neighborRank := func(id int32) (string, error) {
+		rank, err := q.GetColumnRank(ctx, GetColumnRankParams{
+			ID:          id,
+			Status:      column.status,
+			ProjectID:   column.projectID,
+			WorkspaceID: column.workspaceID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) || id == current.ID {
+			return "", ErrInvalidPosition
+		}
+		return rank, err
+	}
+
+	var lower, upper string
+	switch {
+	case position.AfterID != nil:
+		lower, err = neighborRank(*position.AfterID)
+		if err != nil {
+			return pgtype.Text{}, err
+		}
+		// The rank goes right above the next task of the column
+		upper, err = adjacentRank(ctx, q, column, current.ID, lower, true)
+		if err != nil {
+			return pgtype.Text{}, err
+		}
+		if position.BeforeID != nil {
+			var before string
+			before, err = neighborRank(*position.BeforeID)
+			if err != nil {
+				return pgtype.Text{}, err
+			}
+			if before != upper {
+				return pgtype.Text{}, ErrInvalidPosition
+			}
+		}
+	case position.BeforeID != nil:
+		upper, err = neighborRank(*position.BeforeID)
+		if err != nil {
+			return pgtype.Text{}, err
+		}
+		lower, err = adjacentRank(ctx, q, column, current.ID, upper, false)
+	default:
+		lower, err = q.LastColumnRank(ctx, LastColumnRankParams{
+			Status:      column.status,
+			ExcludeID:   current.ID,
+			ProjectID:   column.projectID,
+			WorkspaceID: column.workspaceID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The column is empty, so any rank will do
+			return pgtype.Text{String: current.Rank, Valid: true}, nil
+		}
+	}
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+
+	return pgtype.Text{String: rankBetween(lower, upper), Valid: true}, nil
+}
+
+// adjacentRank returns the closest rank above or below rank among the tasks of the column
+// but the moved one, or an empty string when there is none.
+func adjacentRank(ctx context.Context, q *Queries, column columnKey, excludeID int32, rank string, next bool) (string, error) {
+	var adjacent string
+	var err error
+	if next {
+		adjacent, err = q.NextRank(ctx, NextRankParams{
+			Rank:        rank,
+			ExcludeID:   excludeID,
+			Status:      column.status,
+			ProjectID:   column.projectID,
+			WorkspaceID: column.workspaceID,
+		})
+	} else {
+		adjacent, err = q.PrevRank(ctx, PrevRankParams{
+			Rank:        rank,
+			ExcludeID:   excludeID,
+			Status:      column.status,
+			ProjectID:   column.projectID,
+			WorkspaceID: column.workspaceID,
+		})
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return adjacent, err
+}
+
+// rankBetween returns a rank that sorts strictly between lower and upper, where an empty
+// lower or upper means no bound. Ranks never end in the lowest digit, which guarantees
+// that there is always room between two of them.
+func rankBetween(lower, upper string) string {
+	if upper == "" && lower != "" {
+		// Appending keeps the rank below the ones of tasks created later on
+		return lower + string(rankDigits[len(rankDigits)/2])
+	}
+
+	var rank []byte
+	bounded := upper != ""
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(lower) {
+			lo = strings.IndexByte(rankDigits, lower[i])
+		}
+		hi := len(rankDigits)
+		if bounded && i < len(upper) {
+			hi = strings.IndexByte(rankDigits, upper[i])
+		}
+
+		if hi-lo > 1 {
+			return string(append(rank, rankDigits[(lo+hi)/2]))
+		}
+		rank = append(rank, rankDigits[lo])
+		if hi-lo == 1 {
+			// The rank is now below upper whatever follows
+			bounded = false
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		lower, upper string
+		want         string
+	}{
+		{"", "", "i"},
+		{"", "a", "5"},
+		{"a", "", "ai"},
+		{"a", "c", "b"},
+		// Adjacent digits leave no room on the first position
+		{"a", "b", "ai"},
+		{"ai", "aj", "aii"},
+		{"", "01", "00i"},
+		{"az", "b", "azi"},
+		// Ranks given by the column default of new tasks
+		{"000000000001i", "000000000002i", "000000000001r"},
+		{"000000000001i", "", "000000000001ii"},
+		{"", "000000000001i", "000000000000i"},
+	}
+
+	for _, tt := range tests {
+		got := rankBetween(tt.lower, tt.upper)
+		if got != tt.want {
+			t.Errorf("rankBetween(%q, %q) = %q, want %q", tt.lower, tt.upper, got, tt.want)
+		}
+		if got <= tt.lower || (tt.upper != "" && got >= tt.upper) {
+			t.Errorf("rankBetween(%q, %q) = %q is out of order", tt.lower, tt.upper, got)
+		}
+	}
+}
+
+func TestRankBetweenRepeated(t *testing.T) {
+	// Moving tasks to the same spot over and over must keep finding room
+	lower, upper := "a", "b"
+	for i := 0; i < 200; i++ {
+		rank := rankBetween(lower, upper)
+		if rank <= lower || rank >= upper {
+			t.Fatalf("rankBetween(%q, %q) = %q is out of order", lower, upper, rank)
+		}
+		if strings.HasSuffix(rank, rankDigits[:1]) {
+			t.Fatalf("rankBetween(%q, %q) = %q ends in the lowest digit", lower, upper, rank)
+		}
+		if i%2 == 0 {
+			upper = rank
+		} else {
+			lower = rank
+		}
+	}
+}
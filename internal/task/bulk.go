@@ -0,0 +1,151 @@
+package task
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
+)
+
+// ErrBulkAborted is reported for the operations that were rolled back or skipped because
+// another operation of an all-or-nothing batch failed.
+var ErrBulkAborted = errors.New("bulk operation aborted")
+
+// errBulkRollback makes Bulk roll back an all-or-nothing batch after a failed operation.
+var errBulkRollback = errors.New("bulk transaction rolled back")
+
+type BulkAction string
+
+const (
+	BulkActionSetStatus    BulkAction = "setStatus"
+	BulkActionAddLabels    BulkAction = "addLabels"
+	BulkActionRemoveLabels BulkAction = "removeLabels"
+	BulkActionMoveProject  BulkAction = "moveProject"
+	BulkActionDelete       BulkAction = "delete"
+)
+
+// BulkOperation is a single change of a Bulk call. Only the fields of its action are used,
+// and a nil ProjectID takes the task out of its project.
+type BulkOperation struct {
+	ID     int32
+	Action BulkAction
+	// ExpectedVersion is checked like an If-Match header, AnyVersion skips the check
+	ExpectedVersion int32
+	Status          string
+	Labels          []string
+	ProjectID       *int32
+}
+
+// BulkResult is the outcome of a BulkOperation. Task is nil when the operation failed or
+// deleted the task.
+type BulkResult struct {
+	Task *Task
+	Err  error
+}
+
+// Bulk applies the operations in order within a single transaction. When atomic is set,
+// the first failure rolls back every operation; otherwise each operation runs in its own
+// savepoint and failures only discard their own changes. It reports whether anything was
+// committed.
+func (s Service) Bulk(ctx context.Context, actorID uuid.UUID, ops []BulkOperation, atomic, force bool) ([]BulkResult, bool, error) {
+	results := make([]BulkResult, len(ops))
+
+	err := internal.WithTx(ctx, s.db, func(tx pgx.Tx) error {
+		for i, op := range ops {
+			if atomic {
+				results[i].Task, results[i].Err = applyBulkOperation(ctx, s.queries.WithTx(tx), actorID, op, force)
+				if results[i].Err != nil {
+					s.logWriteError("Failed to apply bulk operation", results[i].Err)
+					abortBulk(results, i)
+					return errBulkRollback
+				}
+				continue
+			}
+
+			results[i].Task, results[i].Err = s.applyInSavepoint(ctx, tx, actorID, op, force)
+			if results[i].Err != nil {
+				s.logWriteError("Failed to apply bulk operation", results[i].Err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkRollback) {
+		return results, false, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to run bulk transaction", zap.Error(err))
+		return nil, false, err
+	}
+
+	s.logger.Info("Applied bulk operations", zap.Int("count", len(ops)), zap.String("actor_id", actorID.String()))
+	return results, true, nil
+}
+
+func (s Service) applyInSavepoint(ctx context.Context, tx pgx.Tx, actorID uuid.UUID, op BulkOperation, force bool) (*Task, error) {
+	var task *Task
+	err := internal.WithTxQueries(ctx, tx, s.queries, func(q *Queries) error {
+		var err error
+		task, err = applyBulkOperation(ctx, q, actorID, op, force)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// abortBulk marks every operation but the failed one as aborted.
+func abortBulk(results []BulkResult, failed int) {
+	for i := range results {
+		if i != failed {
+			results[i] = BulkResult{Err: ErrBulkAborted}
+		}
+	}
+}
+
+func applyBulkOperation(ctx context.Context, q *Queries, actorID uuid.UUID, op BulkOperation, force bool) (*Task, error) {
+	fields := PatchFields{Force: force}
+
+	switch op.Action {
+	case BulkActionDelete:
+		return nil, deleteTask(ctx, q, actorID, op.ID, op.ExpectedVersion)
+	case BulkActionSetStatus:
+		fields.Status = internal.Optional[string]{Set: true, Value: op.Status}
+	case BulkActionMoveProject:
+		fields.ProjectID = internal.Optional[int32]{Set: true, Null: op.ProjectID == nil}
+		if op.ProjectID != nil {
+			fields.ProjectID.Value = *op.ProjectID
+		}
+	case BulkActionAddLabels, BulkActionRemoveLabels:
+		// Lock before reading the labels so that concurrent changes are not lost
+		_, err := lockForWrite(ctx, q, actorID, op.ID, op.ExpectedVersion)
+		if err != nil {
+			return nil, err
+		}
+		names, err := labelNames(ctx, q, op.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Action == BulkActionAddLabels {
+			names = append(names, op.Labels...)
+		} else {
+			names = slices.DeleteFunc(names, func(name string) bool {
+				return slices.ContainsFunc(op.Labels, func(removed string) bool {
+					return strings.EqualFold(strings.TrimSpace(removed), name)
+				})
+			})
+		}
+		fields.Labels = internal.Optional[[]string]{Set: true, Value: names}
+	}
+
+	task, err := patchTask(ctx, q, actorID, op.ID, op.ExpectedVersion, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// AddDependency records that the task is blocked by blockerID.
+func (s Service) AddDependency(ctx context.Context, actorID uuid.UUID, id, blockerID int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		task, err := lockForWrite(ctx, q, actorID, id, AnyVersion)
+		if err != nil {
+			return err
+		}
+
+		if blockerID == id {
+			return ErrDependencyCycle
+		}
+
+		// Lock the blocker as well so that two concurrent inserts cannot close a cycle
+		blocker, err := q.GetByIDForUpdate(ctx, blockerID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrBlockerNotFound
+			}
+			return err
+		}
+		if blocker.WorkspaceID != task.WorkspaceID {
+			return ErrBlockerNotFound
+		}
+
+		cycle, err := q.IsBlockedBy(ctx, IsBlockedByParams{
+			TaskID:      blockerID,
+			CandidateID: id,
+		})
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		err = q.AddDependency(ctx, AddDependencyParams{
+			TaskID:      id,
+			BlockedByID: blockerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The task shows whether it is blocked
+		_, err = q.BumpVersion(ctx, id)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to add task dependency", err)
+		return err
+	}
+	return nil
+}
+
+func (s Service) RemoveDependency(ctx context.Context, actorID uuid.UUID, id, blockerID int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForWrite(ctx, q, actorID, id, AnyVersion)
+		if err != nil {
+			return err
+		}
+
+		err = q.RemoveDependency(ctx, RemoveDependencyParams{
+			TaskID:      id,
+			BlockedByID: blockerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.BumpVersion(ctx, id)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to remove task dependency", err)
+		return err
+	}
+	return nil
+}
+
+// GetDependencies returns the tasks blocking the task and the tasks it blocks.
+func (s Service) GetDependencies(ctx context.Context, viewerID uuid.UUID, id int32) (blockedBy, blocking []Task, err error) {
+	// Dependencies never cross workspaces, so seeing the task is enough
+	_, err = s.GetByID(ctx, viewerID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockedBy, err = s.queries.ListBlockers(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list task blockers", zap.Error(err))
+		return nil, nil, err
+	}
+
+	blocking, err = s.queries.ListBlocking(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list blocked tasks", zap.Error(err))
+		return nil, nil, err
+	}
+	return blockedBy, blocking, nil
+}
+
+// checkBlockers refuses to start or complete a task that still has open blockers,
+// unless force is set.
+func checkBlockers(ctx context.Context, q *Queries, before, after *Task, force bool) error {
+	if force || before.StatusCategory == after.StatusCategory {
+		return nil
+	}
+	if after.StatusCategory == StatusCategoryTODO {
+		return nil
+	}
+
+	blocked, err := isBlocked(ctx, q, after.ID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+func isBlocked(ctx context.Context, q *Queries, id int32) (bool, error) {
+	counts, err := q.CountOpenBlockers(ctx, []int32{id})
+	if err != nil {
+		return false, err
+	}
+	return len(counts) > 0 && counts[0].Count > 0, nil
+}
@@ -0,0 +1,189 @@
+package task
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("invalid task filter")
+
+// Filter narrows a task list. Nil and empty fields are not applied.
+type Filter struct {
+	WorkspaceID *int32
+	ProjectID   *int32
+	// Status is a status key, which may exist in several workflows
+	Status         *string
+	StatusCategory *StatusCategory
+	Priority       *TaskPriority
+	// Labels must all be present on a task, regardless of case
+	Labels     []string
+	AssigneeID *uuid.UUID
+	WatcherID  *uuid.UUID
+	DueAfter   *time.Time
+	DueBefore  *time.Time
+	// Overdue keeps the unfinished tasks that are past their due date
+	Overdue bool
+	// Query is a full-text search over the title and description, in web search syntax
+	Query string
+	// Sort is one of SortOrders, the default being by ID
+	Sort string
+}
+
+// SortOrders are the values accepted by Filter.Sort. A leading "-" sorts in descending order,
+// and tasks without a due date or estimate come last either way.
+var SortOrders = []string{
+	"priority", "-priority",
+	"dueDate", "-dueDate",
+	"estimate", "-estimate",
+	"timeSpent", "-timeSpent",
+	"createdAt", "-createdAt",
+}
+
+// ParseFilter reads the list filters shared by every endpoint that returns tasks
+// from the query string.
+func ParseFilter(r *http.Request) (Filter, error) {
+	query := r.URL.Query()
+	var filter Filter
+
+	if value := query.Get("workspaceId"); value != "" {
+		workspaceID, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || workspaceID <= 0 {
+			return Filter{}, ErrInvalidFilter
+		}
+		id := int32(workspaceID)
+		filter.WorkspaceID = &id
+	}
+
+	if value := query.Get("projectId"); value != "" {
+		projectID, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || projectID <= 0 {
+			return Filter{}, ErrInvalidFilter
+		}
+		id := int32(projectID)
+		filter.ProjectID = &id
+	}
+
+	if value := query.Get("status"); value != "" {
+		filter.Status = &value
+	}
+
+	if value := query.Get("statusCategory"); value != "" {
+		category := StatusCategory(value)
+		if !isValidCategory(category) {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.StatusCategory = &category
+	}
+
+	if value := query.Get("priority"); value != "" {
+		priority := TaskPriority(value)
+		if !isValidPriority(priority) {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.Priority = &priority
+	}
+
+	filter.Labels = query["label"]
+
+	if value := query.Get("dueAfter"); value != "" {
+		dueAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.DueAfter = &dueAfter
+	}
+
+	if value := query.Get("dueBefore"); value != "" {
+		dueBefore, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.DueBefore = &dueBefore
+	}
+
+	if value := query.Get("overdue"); value != "" {
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.Overdue = overdue
+	}
+
+	filter.Query = strings.TrimSpace(query.Get("q"))
+
+	if value := query.Get("sort"); value != "" {
+		if !slices.Contains(SortOrders, value) {
+			return Filter{}, ErrInvalidFilter
+		}
+		filter.Sort = value
+	}
+
+	if value := query.Get("assignee"); value != "" {
+		userID, err := parseUserFilter(r, value)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.AssigneeID = &userID
+	}
+
+	if value := query.Get("watcher"); value != "" {
+		userID, err := parseUserFilter(r, value)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.WatcherID = &userID
+	}
+
+	return filter, nil
+}
+
+// parseUserFilter accepts a user ID or "me" for the authenticated caller.
+func parseUserFilter(r *http.Request, value string) (uuid.UUID, error) {
+	if value == "me" {
+		userID := viewerID(r.Context())
+		if userID == uuid.Nil {
+			return uuid.Nil, ErrInvalidFilter
+		}
+		return userID, nil
+	}
+
+	userID, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, ErrInvalidFilter
+	}
+	return userID, nil
+}
+
+// filterLabels lowercases the label names and drops blanks and duplicates, since GetAll
+// counts the matching labels of a task.
+func filterLabels(names []string) []string {
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(labels, name) {
+			labels = append(labels, name)
+		}
+	}
+	return labels
+}
+
+func isValidCategory(category StatusCategory) bool {
+	switch category {
+	case StatusCategoryTODO, StatusCategoryDOING, StatusCategoryDONE:
+		return true
+	}
+	return false
+}
+
+func isValidPriority(priority TaskPriority) bool {
+	switch priority {
+	case TaskPriorityP0, TaskPriorityP1, TaskPriorityP2, TaskPriorityP3:
+		return true
+	}
+	return false
+}
@@ -2,8 +2,12 @@ package task
 
 import (
 	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
@@ -11,35 +15,235 @@ import (
 )
 
 type Response struct {
-	ID          int32      `json:"id"`
-	Labels      []string   `json:"labels"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      TaskStatus `json:"status"`
-	DueDate     time.Time  `json:"dueDate"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID             int32          `json:"id"`
+	Labels         []string       `json:"labels"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Status         string         `json:"status"`
+	StatusCategory StatusCategory `json:"statusCategory"`
+	DueDate        *time.Time     `json:"dueDate"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	Version        int32          `json:"version"`
+	DeletedAt      *time.Time     `json:"deletedAt,omitempty"`
+	ProjectID      *int32         `json:"projectId"`
+	WorkspaceID    *int32         `json:"workspaceId"`
+	ParentID       *int32         `json:"parentId"`
+	AutoComplete   bool           `json:"autoComplete"`
+	Recurrence     *string        `json:"recurrence"`
+	Occurrence     int32          `json:"occurrence"`
+	Priority       TaskPriority   `json:"priority"`
+	EstimatePoints *int32         `json:"estimatePoints"`
+	// TimeSpentMinutes is read-only, the whole minutes of TimeTracked.TotalSeconds
+	TimeSpentMinutes int64               `json:"timeSpentMinutes"`
+	Rank             string              `json:"rank"`
+	Subtasks         SubtasksResponse    `json:"subtasks"`
+	Blocked          bool                `json:"blocked"`
+	Assignees        []AssigneeResponse  `json:"assignees"`
+	CommentCount     int64               `json:"commentCount"`
+	TimeTracked      TimeTrackedResponse `json:"timeTracked"`
 }
 
+// TimeTrackedResponse totals the time entries of a task, overall and per user. While a
+// timer is running the totals keep growing without the task version changing.
+type TimeTrackedResponse struct {
+	TotalSeconds int64              `json:"totalSeconds"`
+	Running      bool               `json:"running"`
+	Users        []UserTimeResponse `json:"users"`
+}
+
+type UserTimeResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Seconds  int64  `json:"seconds"`
+}
+
+// SubtasksResponse is the completion rollup of a task's direct subtasks.
+type SubtasksResponse struct {
+	Total int64 `json:"total"`
+	Done  int64 `json:"done"`
+}
+
+type AssigneeResponse struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatarUrl"`
+}
+
+type DependenciesResponse struct {
+	BlockedBy []Response `json:"blockedBy"`
+	Blocking  []Response `json:"blocking"`
+}
+
+type WatcherResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatarUrl"`
+	WatchedAt time.Time `json:"watchedAt"`
+}
+
+type EventResponse struct {
+	ID            int64           `json:"id"`
+	Action        TaskEventAction `json:"action"`
+	ActorID       string          `json:"actorId"`
+	ActorUsername string          `json:"actorUsername"`
+	Changes       json.RawMessage `json:"changes"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+type ColumnResponse struct {
+	Status   string         `json:"status"`
+	Name     string         `json:"name"`
+	Category StatusCategory `json:"category"`
+	Tasks    []Response     `json:"tasks"`
+}
+
+type BoardResponse struct {
+	Columns []ColumnResponse `json:"columns"`
+}
+
+type HistoryResponse struct {
+	Events []EventResponse `json:"events"`
+	Total  int64           `json:"total"`
+	Limit  int32           `json:"limit"`
+	Offset int32           `json:"offset"`
+}
+
+// CreateRequest is UpdateRequest plus the workspace. Omitted fields take the defaults of
+// the tasks table, and an omitted status is the first open status of the workflow.
 type CreateRequest struct {
-	Title string `json:"title" validate:"required"`
+	WorkspaceID    *int32       `json:"workspaceId" validate:"omitempty,gt=0"`
+	Labels         []string     `json:"labels" validate:"omitempty,dive,max=50"`
+	Title          string       `json:"title" validate:"required"`
+	Description    string       `json:"description" validate:"omitempty"`
+	Status         string       `json:"status" validate:"omitempty,max=50"`
+	DueDate        time.Time    `json:"dueDate" validate:"omitempty"`
+	ProjectID      *int32       `json:"projectId" validate:"omitempty,gt=0"`
+	ParentID       *int32       `json:"parentId" validate:"omitempty,gt=0"`
+	AutoComplete   bool         `json:"autoComplete"`
+	Recurrence     string       `json:"recurrence" validate:"omitempty,rrule"`
+	Priority       TaskPriority `json:"priority" validate:"omitempty,oneof=P0 P1 P2 P3"`
+	EstimatePoints *int32       `json:"estimatePoints" validate:"omitempty,oneof=0 1 2 3 5 8 13 21"`
 }
 
 type UpdateRequest struct {
-	Labels      []string   `json:"labels" validate:"omitempty"`
-	Title       string     `json:"title" validate:"required"`
-	Description string     `json:"description" validate:"omitempty"`
-	Status      TaskStatus `json:"status" validate:"required,oneof=INBOX TO_DO IN_PROGRESS DONE"`
-	DueDate     time.Time  `json:"dueDate" validate:"omitempty"`
+	Labels         []string     `json:"labels" validate:"omitempty,dive,max=50"`
+	Title          string       `json:"title" validate:"required"`
+	Description    string       `json:"description" validate:"omitempty"`
+	Status         string       `json:"status" validate:"required,max=50"`
+	DueDate        time.Time    `json:"dueDate" validate:"omitempty"`
+	ProjectID      *int32       `json:"projectId" validate:"omitempty,gt=0"`
+	ParentID       *int32       `json:"parentId" validate:"omitempty,gt=0"`
+	AutoComplete   bool         `json:"autoComplete"`
+	Recurrence     string       `json:"recurrence" validate:"omitempty,rrule"`
+	Priority       TaskPriority `json:"priority" validate:"omitempty,oneof=P0 P1 P2 P3"`
+	EstimatePoints *int32       `json:"estimatePoints" validate:"omitempty,oneof=0 1 2 3 5 8 13 21"`
+}
+
+// MoveRequest places a task in a board column. Status defaults to the current one, and
+// without neighbors the task goes to the bottom of the column.
+type MoveRequest struct {
+	Status   string `json:"status" validate:"omitempty,max=50"`
+	AfterID  *int32 `json:"afterId" validate:"omitempty,gt=0"`
+	BeforeID *int32 `json:"beforeId" validate:"omitempty,gt=0"`
+}
+
+// BulkRequest applies operations to several tasks at once. In the default atomic mode a
+// failed operation rolls back all of them, in bestEffort mode only itself.
+type BulkRequest struct {
+	Mode       string                 `json:"mode" validate:"omitempty,oneof=atomic bestEffort"`
+	Force      bool                   `json:"force"`
+	Operations []BulkOperationRequest `json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+type BulkOperationRequest struct {
+	ID     int32      `json:"id" validate:"required,gt=0"`
+	Action BulkAction `json:"action" validate:"required,oneof=setStatus addLabels removeLabels moveProject delete"`
+	// Version is the expected task version, like the If-Match header of single writes
+	Version   int32    `json:"version" validate:"omitempty,gt=0"`
+	Status    string   `json:"status" validate:"required_if=Action setStatus,max=50"`
+	Labels    []string `json:"labels" validate:"required_if=Action addLabels,required_if=Action removeLabels,dive,max=50"`
+	ProjectID *int32   `json:"projectId" validate:"omitempty,gt=0"`
+}
+
+type BulkResultResponse struct {
+	ID     int32      `json:"id"`
+	Action BulkAction `json:"action"`
+	// Status is the HTTP status the operation would have gotten on its own
+	Status int       `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Task   *Response `json:"task,omitempty"`
+}
+
+type BulkResponse struct {
+	Committed bool                 `json:"committed"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Results   []BulkResultResponse `json:"results"`
+}
+
+// PatchRequest is a JSON Merge Patch document for a task. Omitted members are left
+// unchanged and null clears the nullable ones.
+type PatchRequest struct {
+	Labels         internal.Optional[[]string]     `json:"labels"`
+	Title          internal.Optional[string]       `json:"title"`
+	Description    internal.Optional[string]       `json:"description"`
+	Status         internal.Optional[string]       `json:"status"`
+	DueDate        internal.Optional[time.Time]    `json:"dueDate"`
+	ProjectID      internal.Optional[int32]        `json:"projectId"`
+	ParentID       internal.Optional[int32]        `json:"parentId"`
+	AutoComplete   internal.Optional[bool]         `json:"autoComplete"`
+	Recurrence     internal.Optional[string]       `json:"recurrence"`
+	Priority       internal.Optional[TaskPriority] `json:"priority"`
+	EstimatePoints internal.Optional[int32]        `json:"estimatePoints"`
+}
+
+func validatePatchRequest(sl validator.StructLevel) {
+	req := sl.Current().Interface().(PatchRequest)
+
+	internal.ValidateOptional(sl, req.Labels, "labels", "Labels", true, "dive,max=50")
+	internal.ValidateOptional(sl, req.Title, "title", "Title", false, "required")
+	internal.ValidateOptional(sl, req.Description, "description", "Description", true, "")
+	internal.ValidateOptional(sl, req.Status, "status", "Status", false, "required,max=50")
+	internal.ValidateOptional(sl, req.DueDate, "dueDate", "DueDate", true, "")
+	internal.ValidateOptional(sl, req.ProjectID, "projectId", "ProjectID", true, "gt=0")
+	internal.ValidateOptional(sl, req.ParentID, "parentId", "ParentID", true, "gt=0")
+	internal.ValidateOptional(sl, req.AutoComplete, "autoComplete", "AutoComplete", false, "")
+	internal.ValidateOptional(sl, req.Recurrence, "recurrence", "Recurrence", true, "rrule")
+	internal.ValidateOptional(sl, req.Priority, "priority", "Priority", false, "oneof=P0 P1 P2 P3")
+	internal.ValidateOptional(sl, req.EstimatePoints, "estimatePoints", "EstimatePoints", true, "oneof=0 1 2 3 5 8 13 21")
+}
+
+func validateRecurrence(fl validator.FieldLevel) bool {
+	_, err := ParseRecurrence(fl.Field().String())
+	return err == nil
 }
 
 type Store interface {
-	GetAll(ctx context.Context) ([]Task, error)
-	GetByID(ctx context.Context, id int32) (Task, error)
-	Create(ctx context.Context, title string) (Task, error)
-	Update(ctx context.Context, id int32, labels []string, title, description string, status TaskStatus, dueDate time.Time) (Task, error)
-	Delete(ctx context.Context, id int32) error
+	GetAll(ctx context.Context, viewerID uuid.UUID, filter Filter) ([]Task, error)
+	GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (Task, error)
+	Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, fields Fields) (Task, error)
+	Update(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, fields Fields) (Task, error)
+	Patch(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, fields PatchFields) (Task, error)
+	Delete(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32) error
+	Bulk(ctx context.Context, actorID uuid.UUID, ops []BulkOperation, atomic, force bool) ([]BulkResult, bool, error)
+	Move(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, position Position, force bool) (Task, error)
+	GetBoard(ctx context.Context, viewerID uuid.UUID, filter Filter) ([]Column, error)
+	GetTrash(ctx context.Context, viewerID uuid.UUID) ([]Task, error)
+	Restore(ctx context.Context, actorID uuid.UUID, id int32) (Task, error)
+	ListEvents(ctx context.Context, viewerID uuid.UUID, taskID int32, page internal.Pagination) ([]ListEventsRow, int64, error)
+	GetDetails(ctx context.Context, taskIDs ...int32) (map[int32]Details, error)
+	GetSubtasks(ctx context.Context, viewerID uuid.UUID, id int32) ([]Task, error)
+	GetDependencies(ctx context.Context, viewerID uuid.UUID, id int32) (blockedBy, blocking []Task, err error)
+	AddDependency(ctx context.Context, actorID uuid.UUID, id, blockerID int32) error
+	RemoveDependency(ctx context.Context, actorID uuid.UUID, id, blockerID int32) error
+	Assign(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) (Task, error)
+	Unassign(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) (Task, error)
+	ListWatchers(ctx context.Context, viewerID uuid.UUID, id int32) ([]ListWatchersRow, error)
+	Watch(ctx context.Context, actorID uuid.UUID, id int32) error
+	Unwatch(ctx context.Context, actorID uuid.UUID, id int32) error
 }
+
 type Handler struct {
 	logger    *zap.Logger
 	validator *validator.Validate
@@ -47,6 +251,10 @@ type Handler struct {
 }
 
 func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store) *Handler {
+	validator.RegisterStructValidation(validatePatchRequest, PatchRequest{})
+	_ = internal.RegisterValidation(validator, "rrule", validateRecurrence,
+		"must be a recurrence rule with FREQ=DAILY, WEEKLY or MONTHLY")
+
 	return &Handler{
 		logger:    logger,
 		validator: validator,
@@ -54,28 +262,247 @@ func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store)
 	}
 }
 
+// NewResponse renders a task for API clients.
+func NewResponse(task Task, details Details) Response {
+	resp := Response{
+		ID:             task.ID,
+		Labels:         make([]string, len(details.Labels)),
+		Title:          task.Title,
+		Description:    task.Description.String,
+		Status:         task.Status,
+		StatusCategory: task.StatusCategory,
+		CreatedAt:      task.CreatedAt.Time,
+		UpdatedAt:      task.UpdatedAt.Time,
+		Version:        task.Version,
+		AutoComplete:   task.AutoComplete,
+		Occurrence:     task.Occurrence,
+		Priority:       task.Priority,
+		Rank:           task.Rank,
+		Subtasks: SubtasksResponse{
+			Total: details.SubtaskCount,
+			Done:  details.SubtasksDone,
+		},
+		Blocked:      details.OpenBlockers > 0,
+		CommentCount: details.CommentCount,
+	}
+	if task.DueDate.Valid {
+		resp.DueDate = &task.DueDate.Time
+	}
+	if task.DeletedAt.Valid {
+		resp.DeletedAt = &task.DeletedAt.Time
+	}
+	if task.ProjectID.Valid {
+		resp.ProjectID = &task.ProjectID.Int32
+	}
+	if task.WorkspaceID.Valid {
+		resp.WorkspaceID = &task.WorkspaceID.Int32
+	}
+	if task.ParentID.Valid {
+		resp.ParentID = &task.ParentID.Int32
+	}
+	if task.Recurrence.Valid {
+		resp.Recurrence = &task.Recurrence.String
+	}
+	if task.EstimatePoints.Valid {
+		resp.EstimatePoints = &task.EstimatePoints.Int32
+	}
+	for i, label := range details.Labels {
+		resp.Labels[i] = label.Name
+	}
+	resp.TimeTracked.Users = make([]UserTimeResponse, len(details.TimeSpent))
+	for i, spent := range details.TimeSpent {
+		resp.TimeTracked.TotalSeconds += spent.Seconds
+		resp.TimeTracked.Running = resp.TimeTracked.Running || spent.Running
+		resp.TimeTracked.Users[i] = UserTimeResponse{
+			ID:       spent.UserID.String(),
+			Username: spent.Username,
+			Seconds:  spent.Seconds,
+		}
+	}
+	resp.TimeSpentMinutes = resp.TimeTracked.TotalSeconds / 60
+	resp.Assignees = make([]AssigneeResponse, len(details.Assignees))
+	for i, assignee := range details.Assignees {
+		resp.Assignees[i] = AssigneeResponse{
+			ID:        assignee.UserID.String(),
+			Username:  assignee.Username,
+			AvatarURL: assignee.AvatarUrl.String,
+		}
+	}
+	return resp
+}
+
+// NewResponses renders a list of tasks with the details returned by Service.GetDetails.
+func NewResponses(tasks []Task, details map[int32]Details) []Response {
+	resp := make([]Response, len(tasks))
+	for i, task := range tasks {
+		resp[i] = NewResponse(task, details[task.ID])
+	}
+	return resp
+}
+
+// ETag tags the rendered task by its version and the details derived from other tables.
+// Comments, time entries and the like change those details without bumping the version,
+// so they do not make If-Match fail for someone editing the task.
+func (resp Response) ETag() string {
+	derived, _ := json.Marshal([]interface{}{
+		resp.Labels, resp.Subtasks, resp.Blocked, resp.Assignees, resp.CommentCount, resp.TimeTracked,
+	})
+	return internal.ETag(resp.Version, derived)
+}
+
+func (h *Handler) newResponse(ctx context.Context, task Task) (Response, error) {
+	resp, err := h.newResponses(ctx, task)
+	if err != nil {
+		return Response{}, err
+	}
+	return resp[0], nil
+}
+
+// newResponses loads the details of the tasks and renders them.
+func (h *Handler) newResponses(ctx context.Context, tasks ...Task) ([]Response, error) {
+	ids := make([]int32, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	details, err := h.store.GetDetails(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	return NewResponses(tasks, details), nil
+}
+
+// viewerID returns the authenticated user, or uuid.Nil on routes that allow anonymous access.
+func viewerID(ctx context.Context) uuid.UUID {
+	userID, _ := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+	return userID
+}
+
+// storeErrorStatus maps errors returned by the store to an HTTP status and message. It
+// returns an empty message for unexpected errors.
+func storeErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, "Task not found"
+	case errors.Is(err, ErrVersionMismatch):
+		return http.StatusPreconditionFailed, "Task has been modified by another request"
+	case errors.Is(err, ErrProjectNotFound):
+		return http.StatusBadRequest, "Project not found"
+	case errors.Is(err, ErrWorkspaceNotFound):
+		return http.StatusBadRequest, "Workspace not found"
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, "Insufficient workspace role"
+	case errors.Is(err, ErrAssigneeNotFound):
+		return http.StatusBadRequest, "Assignee not found"
+	case errors.Is(err, ErrParentNotFound):
+		return http.StatusBadRequest, "Parent task not found"
+	case errors.Is(err, ErrParentCycle):
+		return http.StatusConflict, "Task cannot be its own ancestor"
+	case errors.Is(err, ErrBlockerNotFound):
+		return http.StatusBadRequest, "Blocking task not found"
+	case errors.Is(err, ErrDependencyCycle):
+		return http.StatusConflict, "Dependency would create a cycle"
+	case errors.Is(err, ErrInvalidStatus):
+		return http.StatusBadRequest, "Status is not part of the project's workflow"
+	case errors.Is(err, ErrTransitionNotAllowed):
+		return http.StatusConflict, "Status transition is not allowed by the project's workflow"
+	case errors.Is(err, ErrInvalidPosition):
+		return http.StatusBadRequest, "Neighbor tasks must be adjacent tasks of the target column"
+	case errors.Is(err, ErrInvalidRecurrence):
+		return http.StatusBadRequest, "Invalid recurrence rule"
+	case errors.Is(err, ErrBlocked):
+		return http.StatusConflict, "Task is blocked by unfinished tasks, retry with force=true to override"
+	case errors.Is(err, ErrBulkAborted):
+		return http.StatusFailedDependency, "Rolled back because another operation failed"
+	default:
+		return http.StatusInternalServerError, ""
+	}
+}
+
+// writeStoreError maps errors returned by the store to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	status, text := storeErrorStatus(err)
+	if text == "" {
+		text = message
+	}
+	http.Error(w, text, status)
+}
+
+// Access is the part of Service that handlers of resources nested under a task use to
+// check the caller's access to it.
+type Access interface {
+	GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (Task, error)
+	CheckWrite(ctx context.Context, actorID uuid.UUID, id int32) error
+}
+
+// PathTaskID extracts the task ID from the URL and makes sure the caller can see the task,
+// or change it when write is set. It writes the error response when it returns false.
+func PathTaskID(w http.ResponseWriter, r *http.Request, logger *zap.Logger, access Access, write bool) (int32, bool) {
+	ctx := r.Context()
+
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	if write {
+		err = access.CheckWrite(ctx, viewerID(ctx), int32(id))
+	} else {
+		_, err = access.GetByID(ctx, viewerID(ctx), int32(id))
+	}
+	if err != nil {
+		logger.Warn("Failed to get task by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get task")
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// expectedVersion reads the If-Match header that every write to an existing task must carry.
+func expectedVersion(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	version, wildcard, err := internal.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		if errors.Is(err, internal.ErrPreconditionRequired) {
+			http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+			return 0, false
+		}
+		http.Error(w, "Task has been modified by another request", http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	if wildcard {
+		return AnyVersion, true
+	}
+	return version, true
+}
+
 func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	tasks, err := h.store.GetAll(ctx)
+	filter, err := ParseFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid task filter", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.store.GetAll(ctx, viewerID(ctx), filter)
 	if err != nil {
 		h.logger.Error("Failed to get all tasks", zap.Error(err))
 		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
 		return
 	}
 
-	var resp = make([]Response, len(tasks))
-	for i, task := range tasks {
-		resp[i] = Response{
-			ID:          task.ID,
-			Labels:      task.Labels,
-			Title:       task.Title,
-			Description: task.Description.String,
-			Status:      task.Status,
-			DueDate:     task.DueDate.Time,
-			CreatedAt:   task.CreatedAt.Time,
-			UpdatedAt:   task.UpdatedAt.Time,
-		}
+	resp, err := h.newResponses(ctx, tasks...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
 	}
 
 	// Write response
@@ -97,23 +524,27 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.store.GetByID(ctx, int32(id))
+	task, err := h.store.GetByID(ctx, viewerID(ctx), int32(id))
 	if err != nil {
 		h.logger.Error("Failed to get task by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, task)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
 		http.Error(w, "Failed to get task", http.StatusInternalServerError)
 		return
 	}
 
-	resp := Response{
-		ID:          task.ID,
-		Labels:      task.Labels,
-		Title:       task.Title,
-		Description: task.Description.String,
-		Status:      task.Status,
-		DueDate:     task.DueDate.Time,
-		CreatedAt:   task.CreatedAt.Time,
-		UpdatedAt:   task.UpdatedAt.Time,
+	etag := resp.ETag()
+	w.Header().Set("ETag", etag)
+	if internal.MatchesIfNoneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
+
 	// Write response
 	internal.WriteJSONResponse(w, http.StatusOK, resp)
 }
@@ -124,29 +555,40 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateRequest
 	err := internal.ParseRequestBody(h.validator, r, &req)
 	if err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
 		return
 	}
 
-	newTask, err := h.store.Create(ctx, req.Title)
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	newTask, err := h.store.Create(ctx, userID, req.WorkspaceID, Fields{
+		Labels:         req.Labels,
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         req.Status,
+		DueDate:        req.DueDate,
+		ProjectID:      req.ProjectID,
+		ParentID:       req.ParentID,
+		AutoComplete:   req.AutoComplete,
+		Recurrence:     req.Recurrence,
+		Priority:       req.Priority,
+		EstimatePoints: req.EstimatePoints,
+	})
 	if err != nil {
 		h.logger.Error("Failed to create task", zap.Error(err))
-		http.Error(w, "Failed to create task", http.StatusInternalServerError)
+		writeStoreError(w, err, "Failed to create task")
 		return
 	}
 
-	resp := Response{
-		ID:          newTask.ID,
-		Labels:      newTask.Labels,
-		Title:       newTask.Title,
-		Description: newTask.Description.String,
-		Status:      newTask.Status,
-		DueDate:     newTask.DueDate.Time,
-		CreatedAt:   newTask.CreatedAt.Time,
-		UpdatedAt:   newTask.UpdatedAt.Time,
+	resp, err := h.newResponse(ctx, newTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to create task", http.StatusInternalServerError)
+		return
 	}
 	// Write response
+	w.Header().Set("ETag", resp.ETag())
 	internal.WriteJSONResponse(w, http.StatusCreated, resp)
 }
 
@@ -166,36 +608,200 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := expectedVersion(w, r)
+	if !ok {
+		return
+	}
+
 	var req UpdateRequest
 	err = internal.ParseRequestBody(h.validator, r, &req)
 	if err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
 		return
 	}
 
-	updatedTask, err := h.store.Update(ctx, int32(id), req.Labels, req.Title, req.Description, req.Status, req.DueDate)
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	updatedTask, err := h.store.Update(ctx, userID, int32(id), version, Fields{
+		Labels:         req.Labels,
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         req.Status,
+		DueDate:        req.DueDate,
+		ProjectID:      req.ProjectID,
+		ParentID:       req.ParentID,
+		AutoComplete:   req.AutoComplete,
+		Recurrence:     req.Recurrence,
+		Priority:       req.Priority,
+		EstimatePoints: req.EstimatePoints,
+		Force:          r.URL.Query().Get("force") == "true",
+	})
 	if err != nil {
 		h.logger.Error("Failed to update task", zap.Error(err))
+		writeStoreError(w, err, "Failed to update task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, updatedTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
 		http.Error(w, "Failed to update task", http.StatusInternalServerError)
 		return
 	}
+	// Write response
+	w.Header().Set("ETag", resp.ETag())
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
 
-	resp := Response{
-		ID:          updatedTask.ID,
-		Labels:      updatedTask.Labels,
-		Title:       updatedTask.Title,
-		Description: updatedTask.Description.String,
-		Status:      updatedTask.Status,
-		DueDate:     updatedTask.DueDate.Time,
-		CreatedAt:   updatedTask.CreatedAt.Time,
-		UpdatedAt:   updatedTask.UpdatedAt.Time,
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := expectedVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req PatchRequest
+	err = internal.ParseMergePatch(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse merge patch", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	patchedTask, err := h.store.Patch(ctx, userID, int32(id), version, PatchFields{
+		Labels:         req.Labels,
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         req.Status,
+		DueDate:        req.DueDate,
+		ProjectID:      req.ProjectID,
+		ParentID:       req.ParentID,
+		AutoComplete:   req.AutoComplete,
+		Recurrence:     req.Recurrence,
+		Priority:       req.Priority,
+		EstimatePoints: req.EstimatePoints,
+		Force:          r.URL.Query().Get("force") == "true",
+	})
+	if err != nil {
+		h.logger.Error("Failed to patch task", zap.Error(err))
+		writeStoreError(w, err, "Failed to update task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, patchedTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to update task", http.StatusInternalServerError)
+		return
 	}
 	// Write response
+	w.Header().Set("ETag", resp.ETag())
 	internal.WriteJSONResponse(w, http.StatusOK, resp)
 }
 
-func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+// Bulk applies a batch of operations in one transaction. The response lists the outcome
+// of every operation; it is 200 when the changes were committed and 409 when an atomic
+// batch was rolled back.
+func (h *Handler) Bulk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req BulkRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	ops := make([]BulkOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = BulkOperation{
+			ID:              op.ID,
+			Action:          op.Action,
+			ExpectedVersion: op.Version,
+			Status:          op.Status,
+			Labels:          op.Labels,
+			ProjectID:       op.ProjectID,
+		}
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	results, committed, err := h.store.Bulk(ctx, userID, ops, req.Mode != "bestEffort", req.Force)
+	if err != nil {
+		h.logger.Error("Failed to apply bulk operations", zap.Error(err))
+		http.Error(w, "Failed to apply bulk operations", http.StatusInternalServerError)
+		return
+	}
+
+	var tasks []Task
+	for _, result := range results {
+		if result.Task != nil {
+			tasks = append(tasks, *result.Task)
+		}
+	}
+	rendered, err := h.newResponses(ctx, tasks...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to apply bulk operations", http.StatusInternalServerError)
+		return
+	}
+
+	resp := BulkResponse{
+		Committed: committed,
+		Results:   make([]BulkResultResponse, len(results)),
+	}
+	for i, result := range results {
+		item := BulkResultResponse{
+			ID:     ops[i].ID,
+			Action: ops[i].Action,
+			Status: http.StatusOK,
+		}
+		switch {
+		case result.Err != nil:
+			item.Status, item.Error = storeErrorStatus(result.Err)
+			if item.Error == "" {
+				item.Error = "Failed to apply operation"
+			}
+			resp.Failed++
+		case result.Task == nil:
+			item.Status = http.StatusNoContent
+			resp.Succeeded++
+		default:
+			item.Task = &rendered[0]
+			rendered = rendered[1:]
+			resp.Succeeded++
+		}
+		resp.Results[i] = item
+	}
+
+	status := http.StatusOK
+	if !committed {
+		status = http.StatusConflict
+	}
+	// Write response
+	internal.WriteJSONResponse(w, status, resp)
+}
+
+// Move drops the task into a board column between the given neighbors.
+func (h *Handler) Move(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Extract task ID from URL
@@ -204,16 +810,523 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Task ID is required", http.StatusBadRequest)
 		return
 	}
+
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.store.Delete(ctx, int32(id))
+	version, ok := expectedVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var req MoveRequest
+	err = internal.ParseRequestBody(h.validator, r, &req)
 	if err != nil {
-		h.logger.Error("Failed to delete task", zap.Error(err))
-		http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	movedTask, err := h.store.Move(ctx, userID, int32(id), version, Position{
+		Status:   req.Status,
+		AfterID:  req.AfterID,
+		BeforeID: req.BeforeID,
+	}, r.URL.Query().Get("force") == "true")
+	if err != nil {
+		h.logger.Error("Failed to move task", zap.Error(err))
+		writeStoreError(w, err, "Failed to move task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, movedTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to move task", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	w.Header().Set("ETag", resp.ETag())
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// GetBoard lists the tasks matching the filter grouped by status, in board order.
+func (h *Handler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := ParseFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid task filter", http.StatusBadRequest)
+		return
+	}
+
+	columns, err := h.store.GetBoard(ctx, viewerID(ctx), filter)
+	if err != nil {
+		h.logger.Error("Failed to get board", zap.Error(err))
+		http.Error(w, "Failed to get board", http.StatusInternalServerError)
+		return
+	}
+
+	var ids []int32
+	for _, column := range columns {
+		for _, task := range column.Tasks {
+			ids = append(ids, task.ID)
+		}
+	}
+	details, err := h.store.GetDetails(ctx, ids...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get board", http.StatusInternalServerError)
+		return
+	}
+
+	resp := BoardResponse{Columns: make([]ColumnResponse, len(columns))}
+	for i, column := range columns {
+		resp.Columns[i] = ColumnResponse{
+			Status:   column.Status.Key,
+			Name:     column.Status.Name,
+			Category: column.Status.Category,
+			Tasks:    NewResponses(column.Tasks, details),
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := expectedVersion(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.Delete(ctx, userID, int32(id), version)
+	if err != nil {
+		h.logger.Error("Failed to delete task", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	tasks, err := h.store.GetTrash(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get trashed tasks", zap.Error(err))
+		http.Error(w, "Failed to get trashed tasks", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.newResponses(ctx, tasks...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get trashed tasks", http.StatusInternalServerError)
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	restoredTask, err := h.store.Restore(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to restore task", zap.Error(err))
+		writeStoreError(w, err, "Failed to restore task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, restoredTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to restore task", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	w.Header().Set("ETag", resp.ETag())
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := internal.ParsePagination(r)
+	if err != nil {
+		http.Error(w, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	events, total, err := h.store.ListEvents(ctx, viewerID(ctx), int32(id), page)
+	if err != nil {
+		h.logger.Error("Failed to list task events", zap.Error(err))
+		writeStoreError(w, err, "Failed to get task history")
+		return
+	}
+
+	resp := HistoryResponse{
+		Events: make([]EventResponse, len(events)),
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for i, event := range events {
+		resp.Events[i] = EventResponse{
+			ID:            event.ID,
+			Action:        event.Action,
+			ActorID:       event.ActorID.String(),
+			ActorUsername: event.ActorUsername.String,
+			Changes:       event.Changes,
+			CreatedAt:     event.CreatedAt.Time,
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Assign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	assigneeID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	updatedTask, err := h.store.Assign(ctx, userID, int32(id), assigneeID)
+	if err != nil {
+		h.logger.Error("Failed to assign task", zap.Error(err))
+		writeStoreError(w, err, "Failed to assign task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, updatedTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to assign task", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	w.Header().Set("ETag", resp.ETag())
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Unassign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	assigneeID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	updatedTask, err := h.store.Unassign(ctx, userID, int32(id), assigneeID)
+	if err != nil {
+		h.logger.Error("Failed to unassign task", zap.Error(err))
+		writeStoreError(w, err, "Failed to unassign task")
+		return
+	}
+
+	resp, err := h.newResponse(ctx, updatedTask)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to unassign task", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	w.Header().Set("ETag", resp.ETag())
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	watchers, err := h.store.ListWatchers(ctx, viewerID(ctx), int32(id))
+	if err != nil {
+		h.logger.Error("Failed to list task watchers", zap.Error(err))
+		writeStoreError(w, err, "Failed to get task watchers")
+		return
+	}
+
+	var resp = make([]WatcherResponse, len(watchers))
+	for i, watcher := range watchers {
+		resp[i] = WatcherResponse{
+			ID:        watcher.UserID.String(),
+			Username:  watcher.Username,
+			AvatarURL: watcher.AvatarUrl.String,
+			WatchedAt: watcher.CreatedAt.Time,
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Watch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.Watch(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to watch task", zap.Error(err))
+		writeStoreError(w, err, "Failed to watch task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Unwatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.Unwatch(ctx, userID, int32(id))
+	if err != nil {
+		h.logger.Error("Failed to unwatch task", zap.Error(err))
+		writeStoreError(w, err, "Failed to unwatch task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSubtasks lists the direct subtasks of a task.
+func (h *Handler) GetSubtasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	subtasks, err := h.store.GetSubtasks(ctx, viewerID(ctx), int32(id))
+	if err != nil {
+		h.logger.Error("Failed to get subtasks", zap.Error(err))
+		writeStoreError(w, err, "Failed to get subtasks")
+		return
+	}
+
+	resp, err := h.newResponses(ctx, subtasks...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get subtasks", http.StatusInternalServerError)
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetDependencies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task ID from URL
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Task ID is required", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	blockedBy, blocking, err := h.store.GetDependencies(ctx, viewerID(ctx), int32(id))
+	if err != nil {
+		h.logger.Error("Failed to get task dependencies", zap.Error(err))
+		writeStoreError(w, err, "Failed to get task dependencies")
+		return
+	}
+
+	var resp DependenciesResponse
+	resp.BlockedBy, err = h.newResponses(ctx, blockedBy...)
+	if err == nil {
+		resp.Blocking, err = h.newResponses(ctx, blocking...)
+	}
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get task dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// AddDependency marks the task as blocked by the task in the blockerId path value.
+func (h *Handler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task IDs from URL
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	blockerID, err := strconv.Atoi(r.PathValue("blockerId"))
+	if err != nil {
+		http.Error(w, "Invalid blocking task ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.AddDependency(ctx, userID, int32(id), int32(blockerID))
+	if err != nil {
+		h.logger.Error("Failed to add task dependency", zap.Error(err))
+		writeStoreError(w, err, "Failed to add task dependency")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract task IDs from URL
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	blockerID, err := strconv.Atoi(r.PathValue("blockerId"))
+	if err != nil {
+		http.Error(w, "Invalid blocking task ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.RemoveDependency(ctx, userID, int32(id), int32(blockerID))
+	if err != nil {
+		h.logger.Error("Failed to remove task dependency", zap.Error(err))
+		writeStoreError(w, err, "Failed to remove task dependency")
 		return
 	}
 
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"slices"
+	"strings"
+)
+
+// replaceLabels sets the labels of the task by name. Names are matched regardless of
+// case, and labels that do not exist yet are created in the task's workspace. It
+// returns the change for the task history, or nil when the labels stayed the same.
+func replaceLabels(ctx context.Context, q *Queries, actorID uuid.UUID, task *Task, names []string) (*FieldChange, error) {
+	before, err := labelNames(ctx, q, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var unique, lowered []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(lowered, strings.ToLower(name)) {
+			continue
+		}
+		unique = append(unique, name)
+		lowered = append(lowered, strings.ToLower(name))
+	}
+
+	err = q.ClearLabels(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(unique) > 0 {
+		err = q.EnsureLabels(ctx, EnsureLabelsParams{
+			WorkspaceID: task.WorkspaceID,
+			OwnerID:     pgtype.UUID{Bytes: actorID, Valid: true},
+			Names:       unique,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = q.AddLabels(ctx, AddLabelsParams{
+			TaskID:      task.ID,
+			WorkspaceID: task.WorkspaceID,
+			Names:       lowered,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	after, err := labelNames(ctx, q, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Equal(before, after) {
+		return nil, nil
+	}
+	return &FieldChange{From: before, To: after}, nil
+}
+
+func labelNames(ctx context.Context, q *Queries, taskID int32) ([]string, error) {
+	labels, err := q.ListLabels(ctx, []int32{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return names, nil
+}
@@ -1,19 +1,351 @@
 -- name: GetAll :many
-SELECT * FROM tasks ORDER BY id ASC;
+SELECT * FROM tasks
+WHERE deleted_at IS NULL
+  AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+  AND (sqlc.narg(workspace_id)::int IS NULL OR workspace_id = sqlc.narg(workspace_id))
+  AND (sqlc.narg(project_id)::int IS NULL OR project_id = sqlc.narg(project_id))
+  AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(status_category)::status_category IS NULL OR status_category = sqlc.narg(status_category))
+  AND (sqlc.narg(priority)::task_priority IS NULL OR priority = sqlc.narg(priority))
+  -- Every label must be present; the names are lowercased and distinct
+  AND (cardinality(sqlc.arg(labels)::text[]) = 0 OR (
+    SELECT count(*) FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+    WHERE tl.task_id = tasks.id AND lower(l.name) = ANY(sqlc.arg(labels)::text[])
+  ) = cardinality(sqlc.arg(labels)::text[]))
+  AND (sqlc.narg(assignee_id)::uuid IS NULL OR EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = tasks.id AND a.user_id = sqlc.narg(assignee_id)))
+  AND (sqlc.narg(watcher_id)::uuid IS NULL OR EXISTS (SELECT 1 FROM task_watchers w WHERE w.task_id = tasks.id AND w.user_id = sqlc.narg(watcher_id)))
+  AND (sqlc.narg(due_after)::timestamptz IS NULL OR due_date >= sqlc.narg(due_after))
+  AND (sqlc.narg(due_before)::timestamptz IS NULL OR due_date < sqlc.narg(due_before))
+  AND (NOT sqlc.arg(overdue)::bool OR (due_date < now() AND status_category <> 'DONE'))
+  AND (sqlc.narg(query)::text IS NULL OR search_vector @@ websearch_to_tsquery('english', sqlc.narg(query)))
+ORDER BY CASE WHEN sqlc.arg(by_rank)::bool THEN rank END ASC,
+         CASE WHEN sqlc.arg(sort)::text = 'priority' THEN priority END ASC,
+         CASE WHEN sqlc.arg(sort)::text = '-priority' THEN priority END DESC,
+         CASE WHEN sqlc.arg(sort)::text = 'dueDate' THEN due_date END ASC NULLS LAST,
+         CASE WHEN sqlc.arg(sort)::text = '-dueDate' THEN due_date END DESC NULLS LAST,
+         CASE WHEN sqlc.arg(sort)::text = 'estimate' THEN estimate_points END ASC NULLS LAST,
+         CASE WHEN sqlc.arg(sort)::text = '-estimate' THEN estimate_points END DESC NULLS LAST,
+         -- Time spent is the total of the time entries, with running timers counted until now
+         CASE WHEN sqlc.arg(sort)::text = 'timeSpent' THEN (
+           SELECT COALESCE(sum(COALESCE(e.ended_at, now()) - e.started_at), INTERVAL '0') FROM time_entries e WHERE e.task_id = tasks.id
+         ) END ASC,
+         CASE WHEN sqlc.arg(sort)::text = '-timeSpent' THEN (
+           SELECT COALESCE(sum(COALESCE(e.ended_at, now()) - e.started_at), INTERVAL '0') FROM time_entries e WHERE e.task_id = tasks.id
+         ) END DESC,
+         CASE WHEN sqlc.arg(sort)::text = 'createdAt' THEN created_at END ASC,
+         CASE WHEN sqlc.arg(sort)::text = '-createdAt' THEN created_at END DESC,
+         id ASC;
 
 -- name: GetByID :one
-SELECT * FROM tasks WHERE id = $1;
+SELECT * FROM tasks
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)));
 
 -- name: Create :one
-INSERT INTO tasks (title)
-VALUES ($1)
+-- A missing due date falls back to the column default
+INSERT INTO tasks (title, description, status, status_category, due_date, project_id, workspace_id, parent_id,
+                   auto_complete, recurrence, priority, estimate_points)
+VALUES (sqlc.arg(title), sqlc.arg(description), sqlc.arg(status), sqlc.arg(status_category),
+        COALESCE(sqlc.narg(due_date), now() + INTERVAL '7 days'), sqlc.narg(project_id), sqlc.narg(workspace_id), sqlc.narg(parent_id),
+        sqlc.arg(auto_complete), sqlc.narg(recurrence), sqlc.arg(priority), sqlc.narg(estimate_points))
 RETURNING *;
 
 -- name: Update :one
 UPDATE tasks
-SET labels = $2, title = $3, description = $4, status = $5, due_date = $6, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
+SET title = sqlc.arg(title), description = sqlc.arg(description), status = sqlc.arg(status), status_category = sqlc.arg(status_category), due_date = sqlc.arg(due_date),
+    project_id = sqlc.narg(project_id), parent_id = sqlc.narg(parent_id), auto_complete = sqlc.arg(auto_complete),
+    recurrence = sqlc.narg(recurrence),
+    priority = sqlc.arg(priority), estimate_points = sqlc.narg(estimate_points),
+    version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL AND (sqlc.arg(expected_version)::int = 0 OR version = sqlc.arg(expected_version))
 RETURNING *;
 
--- name: Delete :exec
-DELETE FROM tasks WHERE id = $1;
\ No newline at end of file
+-- name: Patch :one
+UPDATE tasks
+SET title       = COALESCE(sqlc.narg(title), title),
+    description = CASE WHEN sqlc.arg(set_description)::bool THEN sqlc.narg(description) ELSE description END,
+    status      = COALESCE(sqlc.narg(status), status),
+    status_category = COALESCE(sqlc.narg(status_category), status_category),
+    due_date    = CASE WHEN sqlc.arg(set_due_date)::bool THEN sqlc.narg(due_date) ELSE due_date END,
+    project_id  = CASE WHEN sqlc.arg(set_project_id)::bool THEN sqlc.narg(project_id)::int ELSE project_id END,
+    parent_id   = CASE WHEN sqlc.arg(set_parent_id)::bool THEN sqlc.narg(parent_id)::int ELSE parent_id END,
+    auto_complete = COALESCE(sqlc.narg(auto_complete), auto_complete),
+    recurrence  = CASE WHEN sqlc.arg(set_recurrence)::bool THEN sqlc.narg(recurrence) ELSE recurrence END,
+    priority    = COALESCE(sqlc.narg(priority), priority),
+    estimate_points = CASE WHEN sqlc.arg(set_estimate_points)::bool THEN sqlc.narg(estimate_points)::int ELSE estimate_points END,
+    rank        = COALESCE(sqlc.narg(rank), rank),
+    version     = version + 1,
+    updated_at  = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL AND (sqlc.arg(expected_version)::int = 0 OR version = sqlc.arg(expected_version))
+RETURNING *;
+
+-- name: Delete :execrows
+UPDATE tasks
+SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL AND (sqlc.arg(expected_version)::int = 0 OR version = sqlc.arg(expected_version));
+
+-- name: GetTrash :many
+SELECT * FROM tasks
+WHERE deleted_at IS NOT NULL
+  AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+ORDER BY deleted_at DESC, id ASC;
+
+-- name: GetTrashedByIDForUpdate :one
+SELECT * FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE;
+
+-- name: Restore :one
+UPDATE tasks
+SET deleted_at = NULL, version = version + 1
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeTrash :many
+-- Returns the purged tasks along with the storage keys of their attachments, whose rows
+-- go with the tasks. The select still sees the rows as they were before the delete.
+WITH purged AS (
+    DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING id
+)
+SELECT p.id, a.storage_key
+FROM purged p
+LEFT JOIN task_attachments a ON a.task_id = p.id;
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM tasks WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;
+
+-- name: CreateEvent :exec
+INSERT INTO task_events (task_id, actor_id, action, changes)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListEvents :many
+SELECT e.id, e.task_id, e.actor_id, u.username AS actor_username, e.action, e.changes, e.created_at
+FROM task_events e
+LEFT JOIN users u ON u.id = e.actor_id
+WHERE e.task_id = $1
+ORDER BY e.created_at ASC, e.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: CountEvents :one
+SELECT count(*) FROM task_events WHERE task_id = $1;
+
+-- name: ProjectOwnedBy :one
+SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND owner_id = $2) AS exists;
+
+-- name: GetWorkspaceID :one
+SELECT workspace_id FROM tasks WHERE id = $1;
+
+-- name: GetWorkspaceRole :one
+SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;
+
+-- name: BumpVersion :one
+UPDATE tasks SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+
+-- name: BumpVersions :exec
+UPDATE tasks SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ANY(sqlc.arg(ids)::int[]);
+
+-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1) AS exists;
+
+-- name: ListAssignees :many
+SELECT a.task_id, a.user_id, u.username, u.avatar_url
+FROM task_assignees a
+JOIN users u ON u.id = a.user_id
+WHERE a.task_id = ANY(sqlc.arg(task_ids)::int[])
+ORDER BY a.task_id ASC, a.assigned_at ASC, u.username ASC;
+
+-- name: AddAssignee :execrows
+INSERT INTO task_assignees (task_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (task_id, user_id) DO NOTHING;
+
+-- name: RemoveAssignee :execrows
+DELETE FROM task_assignees WHERE task_id = $1 AND user_id = $2;
+
+-- name: ListWatchers :many
+SELECT w.user_id, u.username, u.avatar_url, w.created_at
+FROM task_watchers w
+JOIN users u ON u.id = w.user_id
+WHERE w.task_id = $1
+ORDER BY w.created_at ASC, u.username ASC;
+
+-- name: AddWatcher :exec
+INSERT INTO task_watchers (task_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (task_id, user_id) DO NOTHING;
+
+-- name: RemoveWatcher :exec
+DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2;
+
+-- name: CountComments :many
+SELECT task_id, count(*) AS count
+FROM task_comments
+WHERE task_id = ANY(sqlc.arg(task_ids)::int[])
+GROUP BY task_id;
+
+-- name: SumTimeEntries :many
+-- Running timers count until now
+SELECT e.task_id, e.user_id, u.username,
+       sum(extract(epoch FROM COALESCE(e.ended_at, now()) - e.started_at))::bigint AS seconds,
+       bool_or(e.ended_at IS NULL)::bool AS running
+FROM time_entries e
+JOIN users u ON u.id = e.user_id
+WHERE e.task_id = ANY(sqlc.arg(task_ids)::int[])
+GROUP BY e.task_id, e.user_id, u.username
+ORDER BY e.task_id ASC, u.username ASC;
+
+-- name: GetSubtasks :many
+SELECT * FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL ORDER BY id ASC;
+
+-- name: CountSubtasks :many
+SELECT parent_id::int AS parent_id, count(*) AS total, count(*) FILTER (WHERE status_category = 'DONE') AS done
+FROM tasks
+WHERE parent_id = ANY(sqlc.arg(task_ids)::int[]) AND deleted_at IS NULL
+GROUP BY parent_id;
+
+-- name: CountOpenSubtasks :one
+SELECT count(*) FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL AND status_category <> 'DONE';
+
+-- name: IsSelfOrDescendant :one
+WITH RECURSIVE descendants AS (
+    SELECT id FROM tasks WHERE id = sqlc.arg(id)
+    UNION
+    SELECT t.id FROM tasks t JOIN descendants d ON t.parent_id = d.id
+)
+SELECT EXISTS (SELECT 1 FROM descendants WHERE descendants.id = sqlc.arg(candidate_id)) AS exists;
+
+-- name: AddDependency :exec
+INSERT INTO task_dependencies (task_id, blocked_by_id)
+VALUES ($1, $2)
+ON CONFLICT (task_id, blocked_by_id) DO NOTHING;
+
+-- name: RemoveDependency :exec
+DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2;
+
+-- name: BumpDependents :exec
+UPDATE tasks SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (SELECT task_id FROM task_dependencies WHERE blocked_by_id = $1);
+
+-- name: ListBlockers :many
+SELECT t.* FROM tasks t
+JOIN task_dependencies d ON d.blocked_by_id = t.id
+WHERE d.task_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.id ASC;
+
+-- name: ListBlocking :many
+SELECT t.* FROM tasks t
+JOIN task_dependencies d ON d.task_id = t.id
+WHERE d.blocked_by_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.id ASC;
+
+-- name: CountOpenBlockers :many
+SELECT d.task_id, count(*) AS count
+FROM task_dependencies d
+JOIN tasks t ON t.id = d.blocked_by_id
+WHERE d.task_id = ANY(sqlc.arg(task_ids)::int[]) AND t.deleted_at IS NULL AND t.status_category <> 'DONE'
+GROUP BY d.task_id;
+
+-- name: IsBlockedBy :one
+WITH RECURSIVE blockers AS (
+    SELECT blocked_by_id AS id FROM task_dependencies WHERE task_dependencies.task_id = sqlc.arg(task_id)
+    UNION
+    SELECT d.blocked_by_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id
+)
+SELECT EXISTS (SELECT 1 FROM blockers WHERE blockers.id = sqlc.arg(candidate_id)) AS exists;
+
+-- name: CreateOccurrence :one
+INSERT INTO tasks (title, description, status, status_category, due_date, project_id, workspace_id, parent_id, auto_complete,
+                   recurrence, occurrence, previous_occurrence_id, priority, estimate_points)
+SELECT title, description, sqlc.arg(status), sqlc.arg(status_category), sqlc.arg(due_date), project_id, workspace_id, parent_id, auto_complete,
+       recurrence, occurrence + 1, id, priority, estimate_points
+FROM tasks
+WHERE id = sqlc.arg(id)
+ON CONFLICT (previous_occurrence_id) DO NOTHING
+RETURNING *;
+
+-- name: CopyAssignees :exec
+INSERT INTO task_assignees (task_id, user_id)
+SELECT sqlc.arg(to_task_id), user_id FROM task_assignees WHERE task_id = sqlc.arg(from_task_id);
+
+-- name: CopyLabels :exec
+INSERT INTO task_labels (task_id, label_id)
+SELECT sqlc.arg(to_task_id), label_id FROM task_labels WHERE task_id = sqlc.arg(from_task_id);
+
+-- name: ListLabels :many
+SELECT tl.task_id, l.id, l.name, l.color
+FROM task_labels tl
+JOIN labels l ON l.id = tl.label_id
+WHERE tl.task_id = ANY(sqlc.arg(task_ids)::int[])
+ORDER BY tl.task_id ASC, lower(l.name) ASC;
+
+-- name: EnsureLabels :exec
+INSERT INTO labels (workspace_id, owner_id, name)
+SELECT sqlc.narg(workspace_id), sqlc.arg(owner_id), unnest(sqlc.arg(names)::text[])
+ON CONFLICT (COALESCE(workspace_id, 0), lower(name)) DO NOTHING;
+
+-- name: ClearLabels :exec
+DELETE FROM task_labels WHERE task_id = $1;
+
+-- name: AddLabels :exec
+INSERT INTO task_labels (task_id, label_id)
+SELECT sqlc.arg(task_id), id FROM labels
+WHERE COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0) AND lower(name) = ANY(sqlc.arg(names)::text[])
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+-- name: GetStatus :one
+SELECT * FROM task_statuses
+WHERE COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0) AND key = sqlc.arg(key);
+
+-- name: FirstStatus :one
+SELECT * FROM task_statuses
+WHERE COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0) AND category = sqlc.arg(category)
+ORDER BY position ASC, id ASC
+LIMIT 1;
+
+-- name: TransitionAllowed :one
+SELECT NOT EXISTS (
+    SELECT 1 FROM task_status_transitions tr
+    JOIN task_statuses s ON s.id = tr.from_status_id
+    WHERE COALESCE(s.project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+) OR EXISTS (
+    SELECT 1 FROM task_status_transitions
+    WHERE from_status_id = sqlc.arg(from_status_id) AND to_status_id = sqlc.arg(to_status_id)
+) AS allowed;
+
+-- name: ListStatuses :many
+SELECT * FROM task_statuses
+WHERE COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+ORDER BY position ASC, id ASC;
+
+-- name: LockRanks :exec
+SELECT pg_advisory_xact_lock(hashtext('tasks_rank'), hashtext(format('%s:%s:%s',
+    COALESCE(sqlc.narg(workspace_id)::int, 0), COALESCE(sqlc.narg(project_id)::int, 0), sqlc.arg(status)::text)));
+
+-- name: GetColumnRank :one
+SELECT rank FROM tasks
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL AND status = sqlc.arg(status)
+  AND COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+  AND COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0);
+
+-- name: LastColumnRank :one
+SELECT rank FROM tasks
+WHERE deleted_at IS NULL AND status = sqlc.arg(status) AND id <> sqlc.arg(exclude_id)
+  AND COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+  AND COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0)
+ORDER BY rank DESC
+LIMIT 1;
+
+-- name: NextRank :one
+SELECT rank FROM tasks
+WHERE rank > sqlc.arg(rank) AND id <> sqlc.arg(exclude_id) AND deleted_at IS NULL AND status = sqlc.arg(status)
+  AND COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+  AND COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0)
+ORDER BY rank ASC
+LIMIT 1;
+
+-- name: PrevRank :one
+SELECT rank FROM tasks
+WHERE rank < sqlc.arg(rank) AND id <> sqlc.arg(exclude_id) AND deleted_at IS NULL AND status = sqlc.arg(status)
+  AND COALESCE(project_id, 0) = COALESCE(sqlc.narg(project_id)::int, 0)
+  AND COALESCE(workspace_id, 0) = COALESCE(sqlc.narg(workspace_id)::int, 0)
+ORDER BY rank DESC
+LIMIT 1;
@@ -0,0 +1,286 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// maxInterval bounds INTERVAL so that the next occurrence stays within a few centuries.
+const maxInterval = 1000
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is the supported subset of an RFC 5545 RRULE: FREQ (DAILY, WEEKLY or MONTHLY),
+// INTERVAL, BYDAY for weekly rules, and either UNTIL or COUNT.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	// Until is zero when the rule has no end date
+	Until time.Time
+	// Count is 0 when the number of occurrences is unbounded
+	Count int
+}
+
+// ParseRecurrence parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". The
+// "RRULE:" prefix is optional.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+
+	r := Recurrence{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" || seen[key] {
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			if r.Freq != FrequencyDaily && r.Freq != FrequencyWeekly && r.Freq != FrequencyMonthly {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 || interval > maxInterval {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return Recurrence{}, ErrInvalidRecurrence
+				}
+				if !slices.Contains(r.ByDay, day) {
+					r.ByDay = append(r.ByDay, day)
+				}
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Until = until
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return Recurrence{}, ErrInvalidRecurrence
+			}
+			r.Count = count
+		default:
+			return Recurrence{}, ErrInvalidRecurrence
+		}
+	}
+
+	if r.Freq == "" || (r.Count > 0 && !r.Until.IsZero()) {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+	if len(r.ByDay) > 0 && r.Freq != FrequencyWeekly {
+		return Recurrence{}, ErrInvalidRecurrence
+	}
+
+	// Keep the days in week order, starting on Monday as RFC 5545 does by default
+	slices.SortFunc(r.ByDay, func(a, b time.Weekday) int {
+		return mondayIndex(a) - mondayIndex(b)
+	})
+	return r, nil
+}
+
+// parseUntil accepts a DATE (the whole day is included) or a UTC DATE-TIME.
+func parseUntil(value string) (time.Time, error) {
+	if len(value) == len("20060102") {
+		date, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return date.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Parse("20060102T150405Z", value)
+}
+
+// String formats the rule in its canonical form.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence following the one due at from, where occurrence is the
+// 1-based number of that occurrence. It returns false once the rule is exhausted.
+func (r Recurrence) Next(from time.Time, occurrence int32) (time.Time, bool) {
+	if r.Count > 0 && int(occurrence) >= r.Count {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	switch r.Freq {
+	case FrequencyDaily:
+		next = from.AddDate(0, 0, r.Interval)
+	case FrequencyWeekly:
+		next = r.nextWeekly(from)
+	case FrequencyMonthly:
+		next = r.nextMonthly(from)
+	default:
+		return time.Time{}, false
+	}
+
+	if !r.Until.IsZero() && next.After(r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Recurrence) nextWeekly(from time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return from.AddDate(0, 0, 7*r.Interval)
+	}
+
+	// A later day in the same week comes first
+	current := mondayIndex(from.Weekday())
+	for _, day := range r.ByDay {
+		if mondayIndex(day) > current {
+			return from.AddDate(0, 0, mondayIndex(day)-current)
+		}
+	}
+
+	weekStart := from.AddDate(0, 0, -current)
+	return weekStart.AddDate(0, 0, 7*r.Interval+mondayIndex(r.ByDay[0]))
+}
+
+// nextMonthly keeps the day of the month and skips months that do not have it,
+// as RFC 5545 does for invalid dates.
+func (r Recurrence) nextMonthly(from time.Time) time.Time {
+	for i := 1; ; i++ {
+		next := time.Date(from.Year(), from.Month()+time.Month(r.Interval*i), from.Day(),
+			from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+		if next.Day() == from.Day() {
+			return next
+		}
+	}
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// recurrenceParam validates a rule and converts it to its canonical form. An empty rule
+// removes the recurrence.
+func recurrenceParam(rule string) (pgtype.Text, error) {
+	if rule == "" {
+		return pgtype.Text{}, nil
+	}
+
+	recurrence, err := ParseRecurrence(rule)
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+	return pgtype.Text{String: recurrence.String(), Valid: true}, nil
+}
+
+// scheduleNextOccurrence creates the next occurrence of a recurring task that has just
+// been completed. The new task is due one recurrence step after the completed one, or
+// after now when the completed task had no due date.
+func scheduleNextOccurrence(ctx context.Context, q *Queries, actorID uuid.UUID, before, after *Task) error {
+	if after.StatusCategory != StatusCategoryDONE || before.StatusCategory == StatusCategoryDONE || !after.Recurrence.Valid {
+		return nil
+	}
+
+	recurrence, err := ParseRecurrence(after.Recurrence.String)
+	if err != nil {
+		return err
+	}
+
+	from := time.Now()
+	if after.DueDate.Valid {
+		from = after.DueDate.Time
+	}
+	dueDate, ok := recurrence.Next(from, after.Occurrence)
+	if !ok {
+		return nil
+	}
+
+	// The next occurrence starts over in the first open status of the workflow
+	status, err := q.FirstStatus(ctx, FirstStatusParams{
+		ProjectID: after.ProjectID,
+		Category:  StatusCategoryTODO,
+	})
+	if err != nil {
+		return err
+	}
+
+	next, err := q.CreateOccurrence(ctx, CreateOccurrenceParams{
+		ID:             after.ID,
+		Status:         status.Key,
+		StatusCategory: status.Category,
+		DueDate:        pgtype.Timestamptz{Time: dueDate, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The next occurrence was created when the task was completed before
+			return nil
+		}
+		return err
+	}
+
+	err = q.CopyAssignees(ctx, CopyAssigneesParams{
+		FromTaskID: after.ID,
+		ToTaskID:   next.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = q.CopyLabels(ctx, CopyLabelsParams{
+		FromTaskID: after.ID,
+		ToTaskID:   next.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordEvent(ctx, q, actorID, next.ID, TaskEventActionCREATE, nil, &next)
+}
@@ -0,0 +1,108 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;byday=th,mo,th;interval=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=WEEKLY;BYDAY=SU,MO", "FREQ=WEEKLY;BYDAY=MO,SU"},
+		{"FREQ=MONTHLY;UNTIL=20261231", "FREQ=MONTHLY;UNTIL=20261231T235959Z"},
+		{"FREQ=MONTHLY;UNTIL=20261231T120000Z", "FREQ=MONTHLY;UNTIL=20261231T120000Z"},
+		{"FREQ=DAILY;COUNT=3", "FREQ=DAILY;COUNT=3"},
+		{"FREQ=MONTHLY;INTERVAL=1000", "FREQ=MONTHLY;INTERVAL=1000"},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("ParseRecurrence(%q) = %s, want %s", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	rules := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=MONTHLY;INTERVAL=1001",
+		"FREQ=MONTHLY;INTERVAL=100000000",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=2026-01-01",
+		"FREQ=DAILY;WKST=MO",
+	}
+
+	for _, rule := range rules {
+		_, err := ParseRecurrence(rule)
+		if !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("ParseRecurrence(%q) = %v, want ErrInvalidRecurrence", rule, err)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+	}
+
+	// The zero want means the rule is exhausted
+	tests := []struct {
+		rule       string
+		from       time.Time
+		occurrence int32
+		want       time.Time
+	}{
+		{"FREQ=DAILY;INTERVAL=3", date(2026, 10, 15), 1, date(2026, 10, 18)},
+		{"FREQ=WEEKLY", date(2026, 10, 15), 1, date(2026, 10, 22)},
+		// Monday to the Thursday of the same week
+		{"FREQ=WEEKLY;BYDAY=MO,TH", date(2026, 10, 12), 1, date(2026, 10, 15)},
+		// Thursday wraps around to the Monday of the next week
+		{"FREQ=WEEKLY;BYDAY=MO,TH", date(2026, 10, 15), 1, date(2026, 10, 19)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", date(2026, 10, 15), 1, date(2026, 10, 26)},
+		// Sunday is the last day of the week
+		{"FREQ=WEEKLY;BYDAY=MO", date(2026, 10, 18), 1, date(2026, 10, 19)},
+		{"FREQ=MONTHLY", date(2026, 10, 15), 1, date(2026, 11, 15)},
+		// Months without the day are skipped
+		{"FREQ=MONTHLY", date(2026, 1, 31), 1, date(2026, 3, 31)},
+		{"FREQ=MONTHLY", date(2026, 8, 31), 1, date(2026, 10, 31)},
+		{"FREQ=MONTHLY", date(2024, 2, 29), 1, date(2024, 3, 29)},
+		{"FREQ=MONTHLY;INTERVAL=12", date(2024, 2, 29), 1, date(2028, 2, 29)},
+		{"FREQ=MONTHLY;INTERVAL=1000", date(2026, 1, 31), 1, date(2109, 5, 31)},
+		{"FREQ=DAILY;COUNT=3", date(2026, 10, 15), 2, date(2026, 10, 16)},
+		{"FREQ=DAILY;COUNT=3", date(2026, 10, 15), 3, time.Time{}},
+		// A date UNTIL includes the whole day
+		{"FREQ=WEEKLY;BYDAY=MO;UNTIL=20261019", date(2026, 10, 15), 1, date(2026, 10, 19)},
+		{"FREQ=WEEKLY;BYDAY=MO;UNTIL=20261018", date(2026, 10, 15), 1, time.Time{}},
+		{"FREQ=DAILY;UNTIL=20261016T085959Z", date(2026, 10, 15), 1, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRecurrence(%q): %v", tt.rule, err)
+		}
+
+		got, ok := r.Next(tt.from, tt.occurrence)
+		if ok != !tt.want.IsZero() || !got.Equal(tt.want) {
+			t.Errorf("%s from %s (occurrence %d) = %s, %v, want %s",
+				tt.rule, tt.from.Format(time.DateOnly), tt.occurrence, got, ok, tt.want)
+		}
+	}
+}
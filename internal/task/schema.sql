@@ -1,12 +1,93 @@
-CREATE TYPE task_status AS ENUM ('INBOX', 'TO_DO', 'IN_PROGRESS', 'DONE');
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE SEQUENCE IF NOT EXISTS tasks_rank_seq;
+
+-- P0 is the most urgent, so that sorting by priority lists urgent tasks first
+CREATE TYPE task_priority AS ENUM ('P0', 'P1', 'P2', 'P3');
 
 CREATE TABLE IF NOT EXISTS tasks (
     id SERIAL NOT NULL PRIMARY KEY,
-    labels TEXT[] DEFAULT ARRAY[]::TEXT[],
     title TEXT NOT NULL,
     description TEXT default '',
-    status task_status NOT NULL DEFAULT 'INBOX',
+    -- Key of a status in the workflow of the task's project, see task_statuses
+    status TEXT NOT NULL DEFAULT 'INBOX',
+    status_category status_category NOT NULL DEFAULT 'TODO',
     due_date TIMESTAMPTZ default now() + INTERVAL '7 days',
     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
-);
\ No newline at end of file
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMPTZ,
+    project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    -- Purging a trashed parent keeps its subtasks as top-level tasks
+    parent_id INTEGER REFERENCES tasks(id) ON DELETE SET NULL,
+    auto_complete BOOLEAN NOT NULL DEFAULT false,
+    -- RFC 5545 RRULE subset, see Recurrence
+    recurrence TEXT,
+    occurrence INTEGER NOT NULL DEFAULT 1,
+    -- Unique so that completing a recurring task twice creates a single next occurrence
+    previous_occurrence_id INTEGER UNIQUE REFERENCES tasks(id) ON DELETE SET NULL,
+    -- Board order within the column given by workspace, project and status, see rankBetween
+    rank TEXT COLLATE "C" NOT NULL DEFAULT lpad(to_hex(nextval('tasks_rank_seq')), 12, '0') || 'i',
+    -- Title matches rank above description matches
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED,
+    priority task_priority NOT NULL DEFAULT 'P2',
+    estimate_points INTEGER CHECK (estimate_points >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_idx ON tasks (project_id);
+CREATE INDEX IF NOT EXISTS tasks_workspace_id_idx ON tasks (workspace_id);
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
+CREATE INDEX IF NOT EXISTS tasks_project_id_status_idx ON tasks (project_id, status);
+CREATE INDEX IF NOT EXISTS tasks_priority_idx ON tasks (priority);
+CREATE INDEX IF NOT EXISTS tasks_column_rank_idx ON tasks (COALESCE(workspace_id, 0), COALESCE(project_id, 0), status, rank)
+    WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS tasks_search_vector_idx ON tasks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS tasks_title_trgm_idx ON tasks USING GIN (title gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TYPE task_event_action AS ENUM ('CREATE', 'UPDATE', 'DELETE', 'RESTORE');
+
+-- task_id has no foreign key so that history outlives the task
+CREATE TABLE IF NOT EXISTS task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    actor_id UUID NOT NULL REFERENCES users(id),
+    action task_event_action NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, created_at);
+
+CREATE TABLE IF NOT EXISTS task_assignees (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_assignees_user_id_idx ON task_assignees (user_id);
+
+CREATE TABLE IF NOT EXISTS task_watchers (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_watchers_user_id_idx ON task_watchers (user_id);
+
+-- task_id is blocked by blocked_by_id until the latter is done
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    blocked_by_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (task_id, blocked_by_id),
+    CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_id_idx ON task_dependencies (blocked_by_id);
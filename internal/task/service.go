@@ -1,26 +1,163 @@
 package task
 
 import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/storage"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 	"time"
 )
 
+// AnyVersion skips the optimistic concurrency check on writes.
+const AnyVersion int32 = 0
+
+var (
+	ErrNotFound        = errors.New("task not found")
+	ErrVersionMismatch = errors.New("task version mismatch")
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrWorkspaceNotFound is returned when creating a task in a workspace the actor is not a member of
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrForbidden         = errors.New("insufficient workspace role")
+	// ErrAssigneeNotFound is returned when assigning a user that does not exist or is not
+	// a member of the task's workspace
+	ErrAssigneeNotFound = errors.New("assignee not found")
+	// ErrParentNotFound is returned when the parent task does not exist or lives in another workspace
+	ErrParentNotFound = errors.New("parent task not found")
+	ErrParentCycle    = errors.New("task cannot be its own ancestor")
+	// ErrBlockerNotFound is returned when the blocking task does not exist or lives in another workspace
+	ErrBlockerNotFound = errors.New("blocking task not found")
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	// ErrBlocked is returned when starting or completing a task with open blockers
+	ErrBlocked = errors.New("task is blocked by unfinished tasks")
+)
+
+// FieldChange is one entry of the field-level diff stored with each task event.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Fields holds the user-editable fields of a task, as replaced by Update.
+type Fields struct {
+	// Labels are label names, see replaceLabels
+	Labels      []string
+	Title       string
+	Description string
+	// Status is the key of a status in the workflow of the task's project
+	Status       string
+	DueDate      time.Time
+	ProjectID    *int32
+	ParentID     *int32
+	AutoComplete bool
+	// Recurrence is an RRULE, see Recurrence. Empty means the task does not recur.
+	Recurrence string
+	// Priority defaults to TaskPriorityP2 when empty
+	Priority       TaskPriority
+	EstimatePoints *int32
+	// Force skips the check that refuses to start or complete a blocked task
+	Force bool
+}
+
+// PatchFields holds the fields changed by Patch. Fields that are not set are left as they are.
+type PatchFields struct {
+	Labels         internal.Optional[[]string]
+	Title          internal.Optional[string]
+	Description    internal.Optional[string]
+	Status         internal.Optional[string]
+	DueDate        internal.Optional[time.Time]
+	ProjectID      internal.Optional[int32]
+	ParentID       internal.Optional[int32]
+	AutoComplete   internal.Optional[bool]
+	Recurrence     internal.Optional[string]
+	Priority       internal.Optional[TaskPriority]
+	EstimatePoints internal.Optional[int32]
+	// Force skips the check that refuses to start or complete a blocked task
+	Force bool
+	// position is set by Move
+	position *Position
+}
+
+// Details holds what is rendered with a task but stored outside its row.
+type Details struct {
+	Labels       []ListLabelsRow
+	Assignees    []ListAssigneesRow
+	CommentCount int64
+	// TimeSpent is the tracked time per user, see the timeentry package
+	TimeSpent    []SumTimeEntriesRow
+	SubtaskCount int64
+	SubtasksDone int64
+	OpenBlockers int64
+}
+
 type Service struct {
 	logger  *zap.Logger
+	db      *pgxpool.Pool
 	queries *Queries
+	// blobs holds the content of attachments, which is removed when tasks are purged
+	blobs storage.BlobStore
 }
 
-func NewService(logger *zap.Logger, db DBTX) *Service {
+func NewService(logger *zap.Logger, db *pgxpool.Pool, blobs storage.BlobStore) *Service {
 	return &Service{
 		logger:  logger,
+		db:      db,
 		queries: New(db),
+		blobs:   blobs,
 	}
 }
 
-func (s Service) GetAll(ctx context.Context) ([]Task, error) {
-	tasks, err := s.queries.GetAll(ctx)
+// GetAll lists the tasks visible to viewerID, which is uuid.Nil for anonymous callers.
+// Tasks that belong to a workspace are only visible to its members.
+func (s Service) GetAll(ctx context.Context, viewerID uuid.UUID, filter Filter) ([]Task, error) {
+	return s.getAll(ctx, viewerID, filter, false)
+}
+
+// getAll lists the tasks by ID, or in board order when byRank is set.
+func (s Service) getAll(ctx context.Context, viewerID uuid.UUID, filter Filter, byRank bool) ([]Task, error) {
+	params := GetAllParams{
+		ViewerID: viewerID,
+		Labels:   filterLabels(filter.Labels),
+		Overdue:  filter.Overdue,
+		Query:    pgtype.Text{String: filter.Query, Valid: filter.Query != ""},
+		ByRank:   byRank,
+		Sort:     filter.Sort,
+	}
+	if filter.WorkspaceID != nil {
+		params.WorkspaceID = pgtype.Int4{Int32: *filter.WorkspaceID, Valid: true}
+	}
+	if filter.AssigneeID != nil {
+		params.AssigneeID = pgtype.UUID{Bytes: *filter.AssigneeID, Valid: true}
+	}
+	if filter.WatcherID != nil {
+		params.WatcherID = pgtype.UUID{Bytes: *filter.WatcherID, Valid: true}
+	}
+	if filter.ProjectID != nil {
+		params.ProjectID = pgtype.Int4{Int32: *filter.ProjectID, Valid: true}
+	}
+	if filter.Status != nil {
+		params.Status = pgtype.Text{String: *filter.Status, Valid: true}
+	}
+	if filter.StatusCategory != nil {
+		params.StatusCategory = NullStatusCategory{StatusCategory: *filter.StatusCategory, Valid: true}
+	}
+	if filter.Priority != nil {
+		params.Priority = NullTaskPriority{TaskPriority: *filter.Priority, Valid: true}
+	}
+	if filter.DueAfter != nil {
+		params.DueAfter = pgtype.Timestamptz{Time: *filter.DueAfter, Valid: true}
+	}
+	if filter.DueBefore != nil {
+		params.DueBefore = pgtype.Timestamptz{Time: *filter.DueBefore, Valid: true}
+	}
+
+	tasks, err := s.queries.GetAll(ctx, params)
 	if err != nil {
 		s.logger.Error("Failed to get all tasks", zap.Error(err))
 		return nil, err
@@ -28,50 +165,953 @@ func (s Service) GetAll(ctx context.Context) ([]Task, error) {
 	return tasks, nil
 }
 
-func (s Service) GetByID(ctx context.Context, id int32) (Task, error) {
-	task, err := s.queries.GetByID(ctx, id)
+func (s Service) GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (Task, error) {
+	task, err := s.queries.GetByID(ctx, GetByIDParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Task{}, ErrNotFound
+		}
 		s.logger.Error("Failed to get task by ID", zap.Error(err))
 		return Task{}, err
 	}
 	return task, nil
 }
 
-func (s Service) Create(ctx context.Context, title string) (Task, error) {
-	task, err := s.queries.Create(ctx, title)
+// CheckWrite makes sure the actor can change the task, for records that other packages
+// attach to tasks. Like the writes of this package, it fails with ErrNotFound for tasks the
+// actor cannot see and ErrForbidden for workspace viewers.
+func (s Service) CheckWrite(ctx context.Context, actorID uuid.UUID, id int32) error {
+	task, err := s.GetByID(ctx, actorID, id)
+	if err != nil {
+		return err
+	}
+
+	err = checkWorkspaceRole(ctx, s.queries, task.WorkspaceID, actorID, true)
 	if err != nil {
-		s.logger.Error("Failed to create task", zap.Error(err))
+		s.logWriteError("Failed to check task write access", err)
+		return err
+	}
+	return nil
+}
+
+// Create adds a task to the workspace. When workspaceID is nil the task belongs to no
+// workspace and is public: anyone can read it and any signed-in user can edit it. An
+// empty status starts the task in the first open status of the project's workflow, and
+// a zero due date falls back to the column default. Force is ignored since a new task
+// has no blockers.
+func (s Service) Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, fields Fields) (Task, error) {
+	var task Task
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		task, err = createTask(ctx, q, actorID, workspaceID, fields)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to create task", err)
 		return Task{}, err
 	}
 	return task, nil
 }
 
-func (s Service) Update(ctx context.Context,
-	id int32,
-	labels []string,
-	title, description string,
-	status TaskStatus,
-	dueDate time.Time) (Task, error) {
-	updatedTask, err := s.queries.Update(ctx, UpdateParams{
-		ID:          id,
-		Labels:      labels,
-		Title:       title,
-		Description: pgtype.Text{String: description, Valid: true},
-		Status:      status,
-		DueDate:     pgtype.Timestamptz{Time: dueDate, Valid: true},
+// CreateTree creates a task along with its subtasks in one transaction, as Create does.
+// The parent of the subtasks is always the new task. It returns the parent first.
+func (s Service) CreateTree(ctx context.Context, actorID uuid.UUID, workspaceID *int32, parent Fields, subtasks []Fields) ([]Task, error) {
+	tasks := make([]Task, 0, len(subtasks)+1)
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		root, err := createTask(ctx, q, actorID, workspaceID, parent)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, root)
+
+		for _, fields := range subtasks {
+			fields.ParentID = &root.ID
+			subtask, err := createTask(ctx, q, actorID, workspaceID, fields)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, subtask)
+		}
+
+		// Adding the subtasks bumped the version of the parent
+		tasks[0], err = q.GetByIDForUpdate(ctx, root.ID)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to create tasks", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func createTask(ctx context.Context, q *Queries, actorID uuid.UUID, workspaceID *int32, fields Fields) (Task, error) {
+	workspaceParam := pgtype.Int4{}
+	if workspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *workspaceID, Valid: true}
+	}
+
+	err := checkWorkspaceRole(ctx, q, workspaceParam, actorID, true)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Task{}, ErrWorkspaceNotFound
+		}
+		return Task{}, err
+	}
+
+	projectParam := pgtype.Int4{}
+	if fields.ProjectID != nil {
+		err = checkProjectOwner(ctx, q, *fields.ProjectID, actorID)
+		if err != nil {
+			return Task{}, err
+		}
+		projectParam = pgtype.Int4{Int32: *fields.ProjectID, Valid: true}
+	}
+
+	parentParam := pgtype.Int4{}
+	if fields.ParentID != nil {
+		// A new task has no subtasks, so it cannot end up in a cycle
+		err = checkParent(ctx, q, workspaceParam, 0, *fields.ParentID)
+		if err != nil {
+			return Task{}, err
+		}
+		parentParam = pgtype.Int4{Int32: *fields.ParentID, Valid: true}
+	}
+
+	var status TaskStatus
+	if fields.Status == "" {
+		status, err = q.FirstStatus(ctx, FirstStatusParams{
+			ProjectID: projectParam,
+			Category:  StatusCategoryTODO,
+		})
+	} else {
+		status, err = resolveStatus(ctx, q, nil, projectParam, fields.Status)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+
+	recurrence, err := recurrenceParam(fields.Recurrence)
+	if err != nil {
+		return Task{}, err
+	}
+
+	priority := fields.Priority
+	if priority == "" {
+		priority = TaskPriorityP2
+	}
+	estimateParam := pgtype.Int4{}
+	if fields.EstimatePoints != nil {
+		estimateParam = pgtype.Int4{Int32: *fields.EstimatePoints, Valid: true}
+	}
+
+	task, err := q.Create(ctx, CreateParams{
+		Title:          fields.Title,
+		Description:    pgtype.Text{String: fields.Description, Valid: true},
+		Status:         status.Key,
+		StatusCategory: status.Category,
+		DueDate:        pgtype.Timestamptz{Time: fields.DueDate, Valid: !fields.DueDate.IsZero()},
+		ProjectID:      projectParam,
+		WorkspaceID:    workspaceParam,
+		ParentID:       parentParam,
+		AutoComplete:   fields.AutoComplete,
+		Recurrence:     recurrence,
+		Priority:       priority,
+		EstimatePoints: estimateParam,
 	})
 	if err != nil {
-		s.logger.Error("Failed to update task", zap.Error(err))
+		return Task{}, err
+	}
+
+	labels, err := replaceLabels(ctx, q, actorID, &task, fields.Labels)
+	if err != nil {
+		return Task{}, err
+	}
+
+	changes := diffTasks(nil, &task)
+	if labels != nil {
+		changes["labels"] = *labels
+	}
+	err = saveEvent(ctx, q, actorID, task.ID, TaskEventActionCREATE, changes)
+	if err != nil {
+		return Task{}, err
+	}
+
+	err = bumpRelated(ctx, q, nil, &task)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// Update replaces the task if its version still equals expectedVersion, or
+// unconditionally when expectedVersion is AnyVersion.
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, fields Fields) (Task, error) {
+	var updatedTask Task
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		current, err := lockForWrite(ctx, q, actorID, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+
+		projectParam := pgtype.Int4{}
+		if fields.ProjectID != nil {
+			err = checkProjectOwner(ctx, q, *fields.ProjectID, actorID)
+			if err != nil {
+				return err
+			}
+			projectParam = pgtype.Int4{Int32: *fields.ProjectID, Valid: true}
+		}
+
+		parentParam := pgtype.Int4{}
+		if fields.ParentID != nil {
+			err = checkParent(ctx, q, current.WorkspaceID, id, *fields.ParentID)
+			if err != nil {
+				return err
+			}
+			parentParam = pgtype.Int4{Int32: *fields.ParentID, Valid: true}
+		}
+
+		status, err := resolveStatus(ctx, q, &current, projectParam, fields.Status)
+		if err != nil {
+			return err
+		}
+
+		recurrence, err := recurrenceParam(fields.Recurrence)
+		if err != nil {
+			return err
+		}
+
+		priority := fields.Priority
+		if priority == "" {
+			priority = TaskPriorityP2
+		}
+		estimateParam := pgtype.Int4{}
+		if fields.EstimatePoints != nil {
+			estimateParam = pgtype.Int4{Int32: *fields.EstimatePoints, Valid: true}
+		}
+
+		updatedTask, err = q.Update(ctx, UpdateParams{
+			ID:              id,
+			ExpectedVersion: expectedVersion,
+			Title:           fields.Title,
+			Description:     pgtype.Text{String: fields.Description, Valid: true},
+			Status:          fields.Status,
+			StatusCategory:  status.Category,
+			DueDate:         pgtype.Timestamptz{Time: fields.DueDate, Valid: !fields.DueDate.IsZero()},
+			ProjectID:       projectParam,
+			ParentID:        parentParam,
+			AutoComplete:    fields.AutoComplete,
+			Recurrence:      recurrence,
+			Priority:        priority,
+			EstimatePoints:  estimateParam,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = checkBlockers(ctx, q, &current, &updatedTask, fields.Force)
+		if err != nil {
+			return err
+		}
+
+		labels, err := replaceLabels(ctx, q, actorID, &updatedTask, fields.Labels)
+		if err != nil {
+			return err
+		}
+
+		err = recordUpdate(ctx, q, actorID, &current, &updatedTask, labels)
+		if err != nil {
+			return err
+		}
+
+		err = scheduleNextOccurrence(ctx, q, actorID, &current, &updatedTask)
+		if err != nil {
+			return err
+		}
+
+		return completeParents(ctx, q, actorID, &current, &updatedTask)
+	})
+	if err != nil {
+		s.logWriteError("Failed to update task", err)
 		return Task{}, err
 	}
 	return updatedTask, nil
 }
 
-func (s Service) Delete(ctx context.Context, id int32) error {
-	err := s.queries.Delete(ctx, id)
+// Patch changes only the fields that are set. A null labels list clears the labels,
+// and a null description, due date, project or parent clears the column.
+func (s Service) Patch(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32, fields PatchFields) (Task, error) {
+	var patchedTask Task
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		patchedTask, err = patchTask(ctx, q, actorID, id, expectedVersion, fields)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to patch task", err)
+		return Task{}, err
+	}
+	return patchedTask, nil
+}
+
+// patchTask is Patch within the caller's transaction.
+func patchTask(ctx context.Context, q *Queries, actorID uuid.UUID, id, expectedVersion int32, fields PatchFields) (Task, error) {
+	params := PatchParams{
+		ID:                id,
+		ExpectedVersion:   expectedVersion,
+		Title:             pgtype.Text{String: fields.Title.Value, Valid: fields.Title.HasValue()},
+		SetDescription:    fields.Description.Set,
+		Description:       pgtype.Text{String: fields.Description.Value, Valid: fields.Description.HasValue()},
+		Status:            pgtype.Text{String: fields.Status.Value, Valid: fields.Status.HasValue()},
+		SetDueDate:        fields.DueDate.Set,
+		DueDate:           pgtype.Timestamptz{Time: fields.DueDate.Value, Valid: fields.DueDate.HasValue()},
+		SetProjectID:      fields.ProjectID.Set,
+		ProjectID:         pgtype.Int4{Int32: fields.ProjectID.Value, Valid: fields.ProjectID.HasValue()},
+		SetParentID:       fields.ParentID.Set,
+		ParentID:          pgtype.Int4{Int32: fields.ParentID.Value, Valid: fields.ParentID.HasValue()},
+		AutoComplete:      pgtype.Bool{Bool: fields.AutoComplete.Value, Valid: fields.AutoComplete.HasValue()},
+		SetRecurrence:     fields.Recurrence.Set,
+		Priority:          NullTaskPriority{TaskPriority: fields.Priority.Value, Valid: fields.Priority.HasValue()},
+		SetEstimatePoints: fields.EstimatePoints.Set,
+		EstimatePoints:    pgtype.Int4{Int32: fields.EstimatePoints.Value, Valid: fields.EstimatePoints.HasValue()},
+	}
+	if fields.Recurrence.HasValue() {
+		recurrence, err := recurrenceParam(fields.Recurrence.Value)
+		if err != nil {
+			return Task{}, err
+		}
+		params.Recurrence = recurrence
+	}
+
+	current, err := lockForWrite(ctx, q, actorID, id, expectedVersion)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if fields.ProjectID.HasValue() {
+		err = checkProjectOwner(ctx, q, fields.ProjectID.Value, actorID)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	if fields.ParentID.HasValue() {
+		err = checkParent(ctx, q, current.WorkspaceID, id, fields.ParentID.Value)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	projectParam := current.ProjectID
+	if fields.ProjectID.Set {
+		projectParam = params.ProjectID
+	}
+	key := current.Status
+	if fields.Status.HasValue() {
+		key = fields.Status.Value
+	}
+
+	// Moving the task to another project has to keep its status valid as well
+	if fields.Status.HasValue() || fields.ProjectID.Set {
+		status, err := resolveStatus(ctx, q, &current, projectParam, key)
+		if err != nil {
+			return Task{}, err
+		}
+		params.StatusCategory = NullStatusCategory{StatusCategory: status.Category, Valid: true}
+	}
+
+	if fields.position != nil {
+		params.Rank, err = placeTask(ctx, q, &current, projectParam, key, *fields.position)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	patchedTask, err := q.Patch(ctx, params)
+	if err != nil {
+		return Task{}, err
+	}
+
+	err = checkBlockers(ctx, q, &current, &patchedTask, fields.Force)
+	if err != nil {
+		return Task{}, err
+	}
+
+	var labels *FieldChange
+	if fields.Labels.Set {
+		labels, err = replaceLabels(ctx, q, actorID, &patchedTask, fields.Labels.Value)
+		if err != nil {
+			return Task{}, err
+		}
+	}
+
+	err = recordUpdate(ctx, q, actorID, &current, &patchedTask, labels)
+	if err != nil {
+		return Task{}, err
+	}
+
+	err = scheduleNextOccurrence(ctx, q, actorID, &current, &patchedTask)
+	if err != nil {
+		return Task{}, err
+	}
+
+	err = completeParents(ctx, q, actorID, &current, &patchedTask)
+	if err != nil {
+		return Task{}, err
+	}
+	return patchedTask, nil
+}
+
+// Delete moves the task to the trash. It is purged for good once the retention period passes.
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, id, expectedVersion int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		return deleteTask(ctx, q, actorID, id, expectedVersion)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete task", err)
+		return err
+	}
+	return nil
+}
+
+// deleteTask is Delete within the caller's transaction.
+func deleteTask(ctx context.Context, q *Queries, actorID uuid.UUID, id, expectedVersion int32) error {
+	current, err := lockForWrite(ctx, q, actorID, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Delete(ctx, DeleteParams{
+		ID:              id,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordEvent(ctx, q, actorID, id, TaskEventActionDELETE, &current, nil)
+}
+
+func (s Service) GetTrash(ctx context.Context, viewerID uuid.UUID) ([]Task, error) {
+	tasks, err := s.queries.GetTrash(ctx, viewerID)
+	if err != nil {
+		s.logger.Error("Failed to get trashed tasks", zap.Error(err))
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s Service) Restore(ctx context.Context, actorID uuid.UUID, id int32) (Task, error) {
+	var restoredTask Task
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		trashedTask, err := q.GetTrashedByIDForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		err = checkWorkspaceRole(ctx, q, trashedTask.WorkspaceID, actorID, true)
+		if err != nil {
+			return err
+		}
+
+		restoredTask, err = q.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = bumpRelated(ctx, q, nil, &restoredTask)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, actorID, id, TaskEventActionRESTORE, &restoredTask, &restoredTask)
+	})
+	if err != nil {
+		s.logWriteError("Failed to restore task", err)
+		return Task{}, err
+	}
+	return restoredTask, nil
+}
+
+// PurgeTrash permanently deletes tasks that have been in the trash for longer than retention,
+// then the content of their attachments.
+func (s Service) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+
+	rows, err := s.queries.PurgeTrash(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		s.logger.Error("Failed to purge trashed tasks", zap.Error(err))
+		return 0, err
+	}
+
+	var purged int64
+	seen := make(map[int32]bool, len(rows))
+	for _, row := range rows {
+		if !seen[row.ID] {
+			seen[row.ID] = true
+			purged++
+		}
+		if !row.StorageKey.Valid {
+			continue
+		}
+
+		// A failure only leaves an orphaned blob behind
+		err = s.blobs.Delete(ctx, row.StorageKey.String)
+		if err != nil {
+			s.logger.Error("Failed to delete attachment content", zap.String("key", row.StorageKey.String), zap.Error(err))
+		}
+	}
+
+	if purged > 0 {
+		s.logger.Info("Purged trashed tasks", zap.Int64("count", purged), zap.Time("cutoff", cutoff))
+	}
+	return purged, nil
+}
+
+// RunTrashPurger calls PurgeTrash every interval until ctx is cancelled.
+func (s Service) RunTrashPurger(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = s.PurgeTrash(ctx, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s Service) ListEvents(ctx context.Context, viewerID uuid.UUID, taskID int32, page internal.Pagination) ([]ListEventsRow, int64, error) {
+	// History stays readable while the task is in the trash, but not once it has been purged
+	workspaceID, err := s.queries.GetWorkspaceID(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrNotFound
+		}
+		s.logger.Error("Failed to get task workspace", zap.Error(err))
+		return nil, 0, err
+	}
+
+	err = checkWorkspaceRole(ctx, s.queries, workspaceID, viewerID, false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events, err := s.queries.ListEvents(ctx, ListEventsParams{
+		TaskID: taskID,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list task events", zap.Error(err))
+		return nil, 0, err
+	}
+
+	total, err := s.queries.CountEvents(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to count task events", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// GetDetails loads what is rendered alongside the given tasks, keyed by task ID.
+func (s Service) GetDetails(ctx context.Context, taskIDs ...int32) (map[int32]Details, error) {
+	labels, err := s.queries.ListLabels(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to list task labels", zap.Error(err))
+		return nil, err
+	}
+
+	assignees, err := s.queries.ListAssignees(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to list task assignees", zap.Error(err))
+		return nil, err
+	}
+
+	commentCounts, err := s.queries.CountComments(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to count task comments", zap.Error(err))
+		return nil, err
+	}
+
+	timeSpent, err := s.queries.SumTimeEntries(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to sum task time entries", zap.Error(err))
+		return nil, err
+	}
+
+	subtaskCounts, err := s.queries.CountSubtasks(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to count subtasks", zap.Error(err))
+		return nil, err
+	}
+
+	blockerCounts, err := s.queries.CountOpenBlockers(ctx, taskIDs)
+	if err != nil {
+		s.logger.Error("Failed to count task blockers", zap.Error(err))
+		return nil, err
+	}
+
+	details := make(map[int32]Details, len(taskIDs))
+	for _, label := range labels {
+		d := details[label.TaskID]
+		d.Labels = append(d.Labels, label)
+		details[label.TaskID] = d
+	}
+	for _, assignee := range assignees {
+		d := details[assignee.TaskID]
+		d.Assignees = append(d.Assignees, assignee)
+		details[assignee.TaskID] = d
+	}
+	for _, count := range commentCounts {
+		d := details[count.TaskID]
+		d.CommentCount = count.Count
+		details[count.TaskID] = d
+	}
+	for _, spent := range timeSpent {
+		d := details[spent.TaskID]
+		d.TimeSpent = append(d.TimeSpent, spent)
+		details[spent.TaskID] = d
+	}
+	for _, count := range subtaskCounts {
+		d := details[count.ParentID]
+		d.SubtaskCount = count.Total
+		d.SubtasksDone = count.Done
+		details[count.ParentID] = d
+	}
+	for _, count := range blockerCounts {
+		d := details[count.TaskID]
+		d.OpenBlockers = count.Count
+		details[count.TaskID] = d
+	}
+	return details, nil
+}
+
+// Assign adds the user to the task's assignees. Assigning changes the task version.
+func (s Service) Assign(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) (Task, error) {
+	return s.changeAssignees(ctx, actorID, id, userID, true)
+}
+
+// Unassign removes the user from the task's assignees.
+func (s Service) Unassign(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) (Task, error) {
+	return s.changeAssignees(ctx, actorID, id, userID, false)
+}
+
+func (s Service) changeAssignees(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID, assign bool) (Task, error) {
+	var task Task
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		task, err = lockForWrite(ctx, q, actorID, id, AnyVersion)
+		if err != nil {
+			return err
+		}
+
+		before, err := assigneeIDs(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		var rows int64
+		if assign {
+			err = checkAssignable(ctx, q, task.WorkspaceID, userID)
+			if err != nil {
+				return err
+			}
+			rows, err = q.AddAssignee(ctx, AddAssigneeParams{TaskID: id, UserID: userID})
+		} else {
+			rows, err = q.RemoveAssignee(ctx, RemoveAssigneeParams{TaskID: id, UserID: userID})
+		}
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			// Nothing changed, so keep the version as it is
+			return nil
+		}
+
+		task, err = q.BumpVersion(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		after, err := assigneeIDs(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		changes, err := json.Marshal(map[string]FieldChange{
+			"assignees": {From: before, To: after},
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.CreateEvent(ctx, CreateEventParams{
+			TaskID:  id,
+			ActorID: actorID,
+			Action:  TaskEventActionUPDATE,
+			Changes: changes,
+		})
+	})
+	if err != nil {
+		s.logWriteError("Failed to change task assignees", err)
+		return Task{}, err
+	}
+	return task, nil
+}
+
+func (s Service) ListWatchers(ctx context.Context, viewerID uuid.UUID, id int32) ([]ListWatchersRow, error) {
+	_, err := s.GetByID(ctx, viewerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	watchers, err := s.queries.ListWatchers(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list task watchers", zap.Error(err))
+		return nil, err
+	}
+	return watchers, nil
+}
+
+// Watch subscribes the actor to the task. Every user who can see a task can watch it.
+func (s Service) Watch(ctx context.Context, actorID uuid.UUID, id int32) error {
+	_, err := s.GetByID(ctx, actorID, id)
 	if err != nil {
-		s.logger.Error("Failed to delete task", zap.Error(err))
+		return err
+	}
+
+	err = s.queries.AddWatcher(ctx, AddWatcherParams{TaskID: id, UserID: actorID})
+	if err != nil {
+		s.logger.Error("Failed to watch task", zap.Error(err))
 		return err
 	}
 	return nil
 }
+
+func (s Service) Unwatch(ctx context.Context, actorID uuid.UUID, id int32) error {
+	err := s.queries.RemoveWatcher(ctx, RemoveWatcherParams{TaskID: id, UserID: actorID})
+	if err != nil {
+		s.logger.Error("Failed to unwatch task", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err,
+		ErrNotFound, ErrVersionMismatch, ErrProjectNotFound, ErrWorkspaceNotFound, ErrForbidden,
+		ErrAssigneeNotFound, ErrParentNotFound, ErrParentCycle, ErrBlockerNotFound, ErrDependencyCycle,
+		ErrBlocked, ErrInvalidRecurrence, ErrInvalidStatus, ErrTransitionNotAllowed, ErrInvalidPosition)
+}
+
+// lockForWrite locks the task row for the rest of the transaction, checks that the actor
+// may write to it and that its version is the expected one.
+func lockForWrite(ctx context.Context, q *Queries, actorID uuid.UUID, id, expectedVersion int32) (Task, error) {
+	task, err := q.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Task{}, ErrNotFound
+		}
+		return Task{}, err
+	}
+
+	err = checkWorkspaceRole(ctx, q, task.WorkspaceID, actorID, true)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if expectedVersion != AnyVersion && task.Version != expectedVersion {
+		return Task{}, ErrVersionMismatch
+	}
+
+	return task, nil
+}
+
+// checkWorkspaceRole makes sure the actor is a member of the workspace, and for writes
+// that the member is not a viewer. Tasks outside any workspace are public and have no
+// owner, so every actor passes.
+func checkWorkspaceRole(ctx context.Context, q *Queries, workspaceID pgtype.Int4, actorID uuid.UUID, write bool) error {
+	if !workspaceID.Valid {
+		return nil
+	}
+
+	role, err := q.GetWorkspaceRole(ctx, GetWorkspaceRoleParams{
+		WorkspaceID: workspaceID.Int32,
+		UserID:      actorID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Hide the task from non-members altogether
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if write && role == WorkspaceRoleVIEWER {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkAssignable makes sure the user exists and, for workspace tasks, is a member of the workspace.
+func checkAssignable(ctx context.Context, q *Queries, workspaceID pgtype.Int4, userID uuid.UUID) error {
+	if workspaceID.Valid {
+		_, err := q.GetWorkspaceRole(ctx, GetWorkspaceRoleParams{
+			WorkspaceID: workspaceID.Int32,
+			UserID:      userID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAssigneeNotFound
+		}
+		return err
+	}
+
+	exists, err := q.UserExists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAssigneeNotFound
+	}
+	return nil
+}
+
+func assigneeIDs(ctx context.Context, q *Queries, taskID int32) ([]string, error) {
+	assignees, err := q.ListAssignees(ctx, []int32{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(assignees))
+	for i, assignee := range assignees {
+		ids[i] = assignee.UserID.String()
+	}
+	return ids, nil
+}
+
+// checkProjectOwner makes sure tasks are only moved into projects the actor owns.
+func checkProjectOwner(ctx context.Context, q *Queries, projectID int32, actorID uuid.UUID) error {
+	owned, err := q.ProjectOwnedBy(ctx, ProjectOwnedByParams{
+		ID:      projectID,
+		OwnerID: actorID,
+	})
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// recordEvent appends an entry to the task history and bumps the tasks whose rendering
+// depends on the task, see bumpRelated. before is nil for creations and after is nil for
+// deletions.
+func recordEvent(ctx context.Context, q *Queries, actorID uuid.UUID, taskID int32, action TaskEventAction, before, after *Task) error {
+	err := saveEvent(ctx, q, actorID, taskID, action, diffTasks(before, after))
+	if err != nil {
+		return err
+	}
+	return bumpRelated(ctx, q, before, after)
+}
+
+// recordUpdate is recordEvent for updates that may also have changed the labels, which
+// are stored outside the task row. labels is nil when they did not change.
+func recordUpdate(ctx context.Context, q *Queries, actorID uuid.UUID, before, after *Task, labels *FieldChange) error {
+	changes := diffTasks(before, after)
+	if labels != nil {
+		changes["labels"] = *labels
+	}
+	err := saveEvent(ctx, q, actorID, after.ID, TaskEventActionUPDATE, changes)
+	if err != nil {
+		return err
+	}
+	return bumpRelated(ctx, q, before, after)
+}
+
+func saveEvent(ctx context.Context, q *Queries, actorID uuid.UUID, taskID int32, action TaskEventAction, diff map[string]FieldChange) error {
+	changes, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateEvent(ctx, CreateEventParams{
+		TaskID:  taskID,
+		ActorID: actorID,
+		Action:  action,
+		Changes: changes,
+	})
+}
+
+// trackedFields lists the user-editable fields of a task by their JSON names.
+func trackedFields(task *Task) map[string]interface{} {
+	fields := map[string]interface{}{
+		"title":          nil,
+		"description":    nil,
+		"status":         nil,
+		"dueDate":        nil,
+		"projectId":      nil,
+		"parentId":       nil,
+		"autoComplete":   nil,
+		"recurrence":     nil,
+		"priority":       nil,
+		"estimatePoints": nil,
+	}
+	if task == nil {
+		return fields
+	}
+
+	fields["title"] = task.Title
+	if task.Description.Valid {
+		fields["description"] = task.Description.String
+	}
+	fields["status"] = task.Status
+	if task.DueDate.Valid {
+		// Normalize so the same instant read back in another location compares equal
+		fields["dueDate"] = task.DueDate.Time.UTC()
+	}
+	if task.ProjectID.Valid {
+		fields["projectId"] = task.ProjectID.Int32
+	}
+	if task.ParentID.Valid {
+		fields["parentId"] = task.ParentID.Int32
+	}
+	fields["autoComplete"] = task.AutoComplete
+	if task.Recurrence.Valid {
+		fields["recurrence"] = task.Recurrence.String
+	}
+	fields["priority"] = task.Priority
+	if task.EstimatePoints.Valid {
+		fields["estimatePoints"] = task.EstimatePoints.Int32
+	}
+
+	return fields
+}
+
+func diffTasks(before, after *Task) map[string]FieldChange {
+	from, to := trackedFields(before), trackedFields(after)
+
+	changes := make(map[string]FieldChange)
+	for name := range from {
+		// Compare encoded values, the form in which changes are stored
+		fromJSON, _ := json.Marshal(from[name])
+		toJSON, _ := json.Marshal(to[name])
+		if !bytes.Equal(fromJSON, toJSON) {
+			changes[name] = FieldChange{From: from[name], To: to[name]}
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrInvalidStatus is returned when the status is not part of the workflow of the task's project
+	ErrInvalidStatus        = errors.New("status is not part of the workflow")
+	ErrTransitionNotAllowed = errors.New("status transition is not allowed by the workflow")
+)
+
+// resolveStatus looks up the status a task moves to in the workflow of projectID and
+// checks that the workflow allows the transition. Moving a task to another project skips
+// the transition check, since its old status belongs to a different workflow. current is
+// nil for new tasks, which may start in any status.
+func resolveStatus(ctx context.Context, q *Queries, current *Task, projectID pgtype.Int4, key string) (TaskStatus, error) {
+	status, err := q.GetStatus(ctx, GetStatusParams{
+		ProjectID: projectID,
+		Key:       key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskStatus{}, ErrInvalidStatus
+		}
+		return TaskStatus{}, err
+	}
+
+	if current == nil || key == current.Status || projectID != current.ProjectID {
+		return status, nil
+	}
+
+	from, err := q.GetStatus(ctx, GetStatusParams{
+		ProjectID: current.ProjectID,
+		Key:       current.Status,
+	})
+	if err != nil {
+		return TaskStatus{}, err
+	}
+
+	allowed, err := q.TransitionAllowed(ctx, TransitionAllowedParams{
+		ProjectID:    projectID,
+		FromStatusID: from.ID,
+		ToStatusID:   status.ID,
+	})
+	if err != nil {
+		return TaskStatus{}, err
+	}
+	if !allowed {
+		return TaskStatus{}, ErrTransitionNotAllowed
+	}
+	return status, nil
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"slices"
+)
+
+// GetSubtasks lists the direct subtasks of a task visible to viewerID.
+func (s Service) GetSubtasks(ctx context.Context, viewerID uuid.UUID, id int32) ([]Task, error) {
+	// Subtasks share the workspace of their parent, so seeing the parent is enough
+	_, err := s.GetByID(ctx, viewerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	subtasks, err := s.queries.GetSubtasks(ctx, pgtype.Int4{Int32: id, Valid: true})
+	if err != nil {
+		s.logger.Error("Failed to get subtasks", zap.Error(err))
+		return nil, err
+	}
+	return subtasks, nil
+}
+
+// checkParent makes sure parentID can become the parent of the task. taskID is 0 for tasks
+// that do not exist yet. The parent row stays locked for the rest of the transaction so that
+// two concurrent moves cannot form a cycle.
+func checkParent(ctx context.Context, q *Queries, workspaceID pgtype.Int4, taskID, parentID int32) error {
+	parent, err := q.GetByIDForUpdate(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrParentNotFound
+		}
+		return err
+	}
+
+	if parent.WorkspaceID != workspaceID {
+		return ErrParentNotFound
+	}
+
+	if taskID == 0 {
+		return nil
+	}
+
+	cycle, err := q.IsSelfOrDescendant(ctx, IsSelfOrDescendantParams{
+		ID:          taskID,
+		CandidateID: parentID,
+	})
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrParentCycle
+	}
+	return nil
+}
+
+// bumpRelated bumps the version of the tasks whose rendering changes when a task goes from
+// before to after, so that their ETags do not go stale. A nil task is one that does not
+// exist or is in the trash. Parents show how many of their subtasks are done, and the
+// tasks blocked by the task whether any of their blockers is still open.
+func bumpRelated(ctx context.Context, q *Queries, before, after *Task) error {
+	if isOpen(before) != isOpen(after) {
+		task := after
+		if task == nil {
+			task = before
+		}
+		err := q.BumpDependents(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var ids []int32
+	if rollupChanged(before, after) {
+		for _, task := range []*Task{before, after} {
+			if task != nil && task.ParentID.Valid && !slices.Contains(ids, task.ParentID.Int32) {
+				ids = append(ids, task.ParentID.Int32)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+	return q.BumpVersions(ctx, ids)
+}
+
+// rollupChanged reports whether the subtask counts of the parents differ between before and after.
+func rollupChanged(before, after *Task) bool {
+	if before == nil || after == nil {
+		return before != after
+	}
+	return before.ParentID != after.ParentID ||
+		(before.StatusCategory == StatusCategoryDONE) != (after.StatusCategory == StatusCategoryDONE)
+}
+
+// isOpen reports whether the task still blocks the tasks that depend on it.
+func isOpen(task *Task) bool {
+	return task != nil && task.StatusCategory != StatusCategoryDONE
+}
+
+// completeParents moves the ancestors of a task that has just been completed to DONE when
+// they have auto-complete enabled and all of their subtasks are done.
+func completeParents(ctx context.Context, q *Queries, actorID uuid.UUID, before, after *Task) error {
+	if after.StatusCategory != StatusCategoryDONE || before.StatusCategory == StatusCategoryDONE {
+		return nil
+	}
+
+	parentID := after.ParentID
+	for parentID.Valid {
+		parent, err := q.GetByIDForUpdate(ctx, parentID.Int32)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// The parent is in the trash
+				return nil
+			}
+			return err
+		}
+		if !parent.AutoComplete || parent.StatusCategory == StatusCategoryDONE {
+			return nil
+		}
+
+		open, err := q.CountOpenSubtasks(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return nil
+		}
+
+		// A blocked parent has to be completed by hand once its blockers are done
+		blocked, err := isBlocked(ctx, q, parent.ID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return nil
+		}
+
+		// Auto-completion is not a user action, so the workflow transitions do not apply
+		done, err := q.FirstStatus(ctx, FirstStatusParams{
+			ProjectID: parent.ProjectID,
+			Category:  StatusCategoryDONE,
+		})
+		if err != nil {
+			return err
+		}
+
+		completed, err := q.Patch(ctx, PatchParams{
+			ID:              parent.ID,
+			ExpectedVersion: AnyVersion,
+			Status:          pgtype.Text{String: done.Key, Valid: true},
+			StatusCategory:  NullStatusCategory{StatusCategory: done.Category, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = recordEvent(ctx, q, actorID, parent.ID, TaskEventActionUPDATE, &parent, &completed)
+		if err != nil {
+			return err
+		}
+
+		parentID = completed.ParentID
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package template
+
+import (
+	"advanced-backend/internal/task"
+	"time"
+)
+
+// Subtask is a subtask created along with the task of a template.
+type Subtask struct {
+	Title       string   `json:"title" validate:"required"`
+	Description string   `json:"description,omitempty"`
+	Labels      []string `json:"labels,omitempty" validate:"omitempty,max=20,dive,max=50"`
+	// DueOffsetDays is relative to the start date, like the template's own offset
+	DueOffsetDays *int32 `json:"dueOffsetDays,omitempty" validate:"omitempty,min=-365,max=365"`
+}
+
+// Fields holds the user-editable fields of a template.
+type Fields struct {
+	Name          string
+	Title         string
+	Description   string
+	Labels        []string
+	DueOffsetDays *int32
+	Subtasks      []Subtask
+}
+
+// dueDate offsets start by whole days, keeping its time of day across DST changes. Without
+// an offset it returns the zero time, so that the task gets the default due date.
+func dueDate(start time.Time, offsetDays *int32) time.Time {
+	if offsetDays == nil {
+		return time.Time{}
+	}
+	return start.AddDate(0, 0, int(*offsetDays))
+}
+
+// taskFields computes the task and subtasks created from the template.
+func taskFields(template TaskTemplate, subtasks []Subtask, start time.Time, projectID *int32) (task.Fields, []task.Fields) {
+	var offset *int32
+	if template.DueOffsetDays.Valid {
+		offset = &template.DueOffsetDays.Int32
+	}
+	parent := task.Fields{
+		Labels:      template.Labels,
+		Title:       template.Title,
+		Description: template.Description,
+		DueDate:     dueDate(start, offset),
+		ProjectID:   projectID,
+	}
+
+	children := make([]task.Fields, len(subtasks))
+	for i, subtask := range subtasks {
+		children[i] = task.Fields{
+			Labels:      subtask.Labels,
+			Title:       subtask.Title,
+			Description: subtask.Description,
+			DueDate:     dueDate(start, subtask.DueOffsetDays),
+			ProjectID:   projectID,
+		}
+	}
+	return parent, children
+}
@@ -0,0 +1,390 @@
+package template
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Response struct {
+	ID            int32     `json:"id"`
+	OwnerID       string    `json:"ownerId"`
+	WorkspaceID   *int32    `json:"workspaceId"`
+	Name          string    `json:"name"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Labels        []string  `json:"labels"`
+	DueOffsetDays *int32    `json:"dueOffsetDays"`
+	Subtasks      []Subtask `json:"subtasks"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type CreateRequest struct {
+	Name          string    `json:"name" validate:"required,max=100"`
+	WorkspaceID   *int32    `json:"workspaceId" validate:"omitempty,gt=0"`
+	Title         string    `json:"title" validate:"required"`
+	Description   string    `json:"description"`
+	Labels        []string  `json:"labels" validate:"omitempty,max=20,dive,max=50"`
+	DueOffsetDays *int32    `json:"dueOffsetDays" validate:"omitempty,min=-365,max=365"`
+	Subtasks      []Subtask `json:"subtasks" validate:"omitempty,max=100,dive"`
+}
+
+type UpdateRequest struct {
+	Name          string    `json:"name" validate:"required,max=100"`
+	Title         string    `json:"title" validate:"required"`
+	Description   string    `json:"description"`
+	Labels        []string  `json:"labels" validate:"omitempty,max=20,dive,max=50"`
+	DueOffsetDays *int32    `json:"dueOffsetDays" validate:"omitempty,min=-365,max=365"`
+	Subtasks      []Subtask `json:"subtasks" validate:"omitempty,max=100,dive"`
+}
+
+// InstantiateRequest sets the date the due offsets of the template count from, and
+// optionally the project the new tasks go to.
+type InstantiateRequest struct {
+	StartDate time.Time `json:"startDate" validate:"required"`
+	ProjectID *int32    `json:"projectId" validate:"omitempty,gt=0"`
+}
+
+type Store interface {
+	List(ctx context.Context, viewerID uuid.UUID) ([]TaskTemplate, error)
+	GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (TaskTemplate, error)
+	Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, fields Fields) (TaskTemplate, error)
+	Update(ctx context.Context, actorID uuid.UUID, id int32, fields Fields) (TaskTemplate, error)
+	Delete(ctx context.Context, actorID uuid.UUID, id int32) error
+}
+
+// taskStore creates the tasks of a template through the regular task service.
+type taskStore interface {
+	CreateTree(ctx context.Context, actorID uuid.UUID, workspaceID *int32, parent task.Fields, subtasks []task.Fields) ([]task.Task, error)
+	GetDetails(ctx context.Context, taskIDs ...int32) (map[int32]task.Details, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+	taskStore taskStore
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store, taskStore taskStore) *Handler {
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+		taskStore: taskStore,
+	}
+}
+
+func decodeSubtasks(template TaskTemplate) ([]Subtask, error) {
+	var subtasks []Subtask
+	err := json.Unmarshal(template.Subtasks, &subtasks)
+	return subtasks, err
+}
+
+func newResponse(template TaskTemplate) (Response, error) {
+	subtasks, err := decodeSubtasks(template)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp := Response{
+		ID:          template.ID,
+		OwnerID:     template.OwnerID.String(),
+		Name:        template.Name,
+		Title:       template.Title,
+		Description: template.Description,
+		Labels:      template.Labels,
+		Subtasks:    subtasks,
+		CreatedAt:   template.CreatedAt.Time,
+		UpdatedAt:   template.UpdatedAt.Time,
+	}
+	if template.WorkspaceID.Valid {
+		resp.WorkspaceID = &template.WorkspaceID.Int32
+	}
+	if template.DueOffsetDays.Valid {
+		resp.DueOffsetDays = &template.DueOffsetDays.Int32
+	}
+	return resp, nil
+}
+
+// writeStoreError maps errors returned by the template and task stores to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Template not found", http.StatusNotFound)
+	case errors.Is(err, ErrWorkspaceNotFound), errors.Is(err, task.ErrWorkspaceNotFound):
+		http.Error(w, "Workspace not found", http.StatusBadRequest)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Only the owner can change a template", http.StatusForbidden)
+	case errors.Is(err, task.ErrForbidden):
+		http.Error(w, "Insufficient workspace role", http.StatusForbidden)
+	case errors.Is(err, task.ErrProjectNotFound):
+		http.Error(w, "Project not found", http.StatusBadRequest)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// pathTemplateID extracts the template ID from the URL, writing a 400 response when it is invalid.
+func pathTemplateID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Template ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// templateName trims the name, writing a 400 response when nothing is left.
+func templateName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		http.Error(w, "Template name is required", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// GetAll lists the caller's templates and the templates of their workspaces.
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	templates, err := h.store.List(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get templates", zap.Error(err))
+		writeStoreError(w, err, "Failed to get templates")
+		return
+	}
+
+	resp := make([]Response, len(templates))
+	for i, template := range templates {
+		resp[i], err = newResponse(template)
+		if err != nil {
+			h.logger.Error("Failed to decode template subtasks", zap.Error(err))
+			http.Error(w, "Failed to get templates", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	template, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get template by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get template")
+		return
+	}
+
+	resp, err := newResponse(template)
+	if err != nil {
+		h.logger.Error("Failed to decode template subtasks", zap.Error(err))
+		http.Error(w, "Failed to get template", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := templateName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	template, err := h.store.Create(ctx, userID, req.WorkspaceID, Fields{
+		Name:          name,
+		Title:         req.Title,
+		Description:   req.Description,
+		Labels:        req.Labels,
+		DueOffsetDays: req.DueOffsetDays,
+		Subtasks:      req.Subtasks,
+	})
+	if err != nil {
+		h.logger.Error("Failed to create template", zap.Error(err))
+		writeStoreError(w, err, "Failed to create template")
+		return
+	}
+
+	resp, err := newResponse(template)
+	if err != nil {
+		h.logger.Error("Failed to decode template subtasks", zap.Error(err))
+		http.Error(w, "Failed to create template", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := templateName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	template, err := h.store.Update(ctx, userID, id, Fields{
+		Name:          name,
+		Title:         req.Title,
+		Description:   req.Description,
+		Labels:        req.Labels,
+		DueOffsetDays: req.DueOffsetDays,
+		Subtasks:      req.Subtasks,
+	})
+	if err != nil {
+		h.logger.Error("Failed to update template", zap.Error(err))
+		writeStoreError(w, err, "Failed to update template")
+		return
+	}
+
+	resp, err := newResponse(template)
+	if err != nil {
+		h.logger.Error("Failed to decode template subtasks", zap.Error(err))
+		http.Error(w, "Failed to update template", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete template", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Instantiate creates the task of the template with its subtasks, in the template's
+// workspace. It responds with the new tasks, the parent first.
+func (h *Handler) Instantiate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathTemplateID(w, r)
+	if !ok {
+		return
+	}
+
+	var req InstantiateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	template, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get template by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to instantiate template")
+		return
+	}
+
+	subtasks, err := decodeSubtasks(template)
+	if err != nil {
+		h.logger.Error("Failed to decode template subtasks", zap.Error(err))
+		http.Error(w, "Failed to instantiate template", http.StatusInternalServerError)
+		return
+	}
+
+	var workspaceID *int32
+	if template.WorkspaceID.Valid {
+		workspaceID = &template.WorkspaceID.Int32
+	}
+	parent, children := taskFields(template, subtasks, req.StartDate, req.ProjectID)
+
+	tasks, err := h.taskStore.CreateTree(ctx, userID, workspaceID, parent, children)
+	if err != nil {
+		h.logger.Error("Failed to instantiate template", zap.Error(err))
+		writeStoreError(w, err, "Failed to instantiate template")
+		return
+	}
+
+	ids := make([]int32, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	details, err := h.taskStore.GetDetails(ctx, ids...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to instantiate template", http.StatusInternalServerError)
+		return
+	}
+
+	resp := task.NewResponses(tasks, details)
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,31 @@
+-- name: List :many
+SELECT * FROM task_templates
+WHERE owner_id = sqlc.arg(viewer_id)
+   OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id))
+ORDER BY lower(name) ASC, id ASC;
+
+-- name: GetByID :one
+SELECT * FROM task_templates
+WHERE id = sqlc.arg(id)
+  AND (owner_id = sqlc.arg(viewer_id)
+   OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)));
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM task_templates WHERE id = $1 FOR UPDATE;
+
+-- name: Create :one
+INSERT INTO task_templates (owner_id, workspace_id, name, title, description, labels, due_offset_days, subtasks)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: Update :one
+UPDATE task_templates
+SET name = $2, title = $3, description = $4, labels = $5, due_offset_days = $6, subtasks = $7, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM task_templates WHERE id = $1;
+
+-- name: IsWorkspaceMember :one
+SELECT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2) AS exists;
@@ -0,0 +1,205 @@
+package template
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound          = errors.New("template not found")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	// ErrForbidden is returned when changing a workspace template created by someone else
+	ErrForbidden = errors.New("template is owned by another user")
+)
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+// List returns the viewer's own templates along with the templates of their workspaces.
+func (s Service) List(ctx context.Context, viewerID uuid.UUID) ([]TaskTemplate, error) {
+	templates, err := s.queries.List(ctx, viewerID)
+	if err != nil {
+		s.logger.Error("Failed to list templates", zap.Error(err))
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s Service) GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (TaskTemplate, error) {
+	template, err := s.queries.GetByID(ctx, GetByIDParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskTemplate{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get template by ID", zap.Error(err))
+		return TaskTemplate{}, err
+	}
+	return template, nil
+}
+
+// Create saves a template. A template in a workspace can only be created by its members.
+func (s Service) Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, fields Fields) (TaskTemplate, error) {
+	workspaceParam := pgtype.Int4{}
+	if workspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *workspaceID, Valid: true}
+
+		member, err := s.queries.IsWorkspaceMember(ctx, IsWorkspaceMemberParams{
+			WorkspaceID: *workspaceID,
+			UserID:      actorID,
+		})
+		if err != nil {
+			s.logger.Error("Failed to check workspace membership", zap.Error(err))
+			return TaskTemplate{}, err
+		}
+		if !member {
+			return TaskTemplate{}, ErrWorkspaceNotFound
+		}
+	}
+
+	subtasks, err := encodeSubtasks(fields.Subtasks)
+	if err != nil {
+		return TaskTemplate{}, err
+	}
+
+	template, err := s.queries.Create(ctx, CreateParams{
+		OwnerID:       actorID,
+		WorkspaceID:   workspaceParam,
+		Name:          fields.Name,
+		Title:         fields.Title,
+		Description:   fields.Description,
+		Labels:        labelsParam(fields.Labels),
+		DueOffsetDays: offsetParam(fields.DueOffsetDays),
+		Subtasks:      subtasks,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create template", zap.Error(err))
+		return TaskTemplate{}, err
+	}
+
+	s.logger.Info("Created template", zap.Int32("template_id", template.ID), zap.String("owner_id", actorID.String()))
+	return template, nil
+}
+
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, id int32, fields Fields) (TaskTemplate, error) {
+	subtasks, err := encodeSubtasks(fields.Subtasks)
+	if err != nil {
+		return TaskTemplate{}, err
+	}
+
+	var template TaskTemplate
+	err = internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+
+		template, err = q.Update(ctx, UpdateParams{
+			ID:            id,
+			Name:          fields.Name,
+			Title:         fields.Title,
+			Description:   fields.Description,
+			Labels:        labelsParam(fields.Labels),
+			DueOffsetDays: offsetParam(fields.DueOffsetDays),
+			Subtasks:      subtasks,
+		})
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to update template", err)
+		return TaskTemplate{}, err
+	}
+	return template, nil
+}
+
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+		return q.Delete(ctx, id)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete template", err)
+		return err
+	}
+
+	s.logger.Info("Deleted template", zap.Int32("template_id", id))
+	return nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err, ErrNotFound, ErrForbidden)
+}
+
+// lockForOwner locks the template for the rest of the transaction. Templates of the actor's
+// workspaces that someone else created are reported as forbidden, and other templates as
+// not found.
+func lockForOwner(ctx context.Context, q *Queries, actorID uuid.UUID, id int32) (TaskTemplate, error) {
+	_, err := q.GetByID(ctx, GetByIDParams{
+		ID:       id,
+		ViewerID: actorID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskTemplate{}, ErrNotFound
+		}
+		return TaskTemplate{}, err
+	}
+
+	template, err := q.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TaskTemplate{}, ErrNotFound
+		}
+		return TaskTemplate{}, err
+	}
+	if template.OwnerID != actorID {
+		return TaskTemplate{}, ErrForbidden
+	}
+	return template, nil
+}
+
+func encodeSubtasks(subtasks []Subtask) ([]byte, error) {
+	if subtasks == nil {
+		// The column holds a JSON array even without subtasks
+		subtasks = []Subtask{}
+	}
+	return json.Marshal(subtasks)
+}
+
+// labelsParam keeps the column non-null when the template has no labels.
+func labelsParam(labels []string) []string {
+	if labels == nil {
+		return []string{}
+	}
+	return labels
+}
+
+func offsetParam(offsetDays *int32) pgtype.Int4 {
+	if offsetDays == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *offsetDays, Valid: true}
+}
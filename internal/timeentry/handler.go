@@ -0,0 +1,401 @@
+package timeentry
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultReportRange is covered by a report without ?from=
+	DefaultReportRange = 30 * 24 * time.Hour
+	MaxReportRange     = 366 * 24 * time.Hour
+)
+
+type Response struct {
+	ID        int32      `json:"id"`
+	TaskID    int32      `json:"taskId"`
+	UserID    string     `json:"userId"`
+	Username  string     `json:"username"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+	// Seconds runs up to now while the timer is running
+	Seconds   int64     `json:"seconds"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ListResponse struct {
+	Entries []Response `json:"entries"`
+	Total   int64      `json:"total"`
+	Limit   int32      `json:"limit"`
+	Offset  int32      `json:"offset"`
+}
+
+type ReportRowResponse struct {
+	TaskID    int32  `json:"taskId"`
+	TaskTitle string `json:"taskTitle"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	Seconds   int64  `json:"seconds"`
+}
+
+type ReportResponse struct {
+	From         time.Time           `json:"from"`
+	To           time.Time           `json:"to"`
+	TotalSeconds int64               `json:"totalSeconds"`
+	Rows         []ReportRowResponse `json:"rows"`
+}
+
+// Request is a manually logged time entry.
+type Request struct {
+	StartedAt time.Time `json:"startedAt" validate:"required"`
+	EndedAt   time.Time `json:"endedAt" validate:"required,gtefield=StartedAt"`
+	Note      string    `json:"note" validate:"max=1000"`
+}
+
+type Store interface {
+	List(ctx context.Context, taskID int32, page internal.Pagination) ([]Entry, int64, error)
+	Create(ctx context.Context, userID uuid.UUID, taskID int32, startedAt, endedAt time.Time, note string) (Entry, error)
+	Update(ctx context.Context, actorID uuid.UUID, taskID, id int32, startedAt, endedAt time.Time, note string) (Entry, error)
+	Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error
+	StartTimer(ctx context.Context, userID uuid.UUID, taskID int32) (Entry, error)
+	StopTimer(ctx context.Context, userID uuid.UUID, taskID int32) (Entry, error)
+	Report(ctx context.Context, viewerID uuid.UUID, filter ReportFilter) ([]ReportRow, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+	taskStore task.Access
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store, taskStore task.Access) *Handler {
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+		taskStore: taskStore,
+	}
+}
+
+func newResponse(entry Entry) Response {
+	resp := Response{
+		ID:        entry.ID,
+		TaskID:    entry.TaskID,
+		UserID:    entry.UserID.String(),
+		Username:  entry.Username,
+		StartedAt: entry.StartedAt.Time,
+		Note:      entry.Note,
+		CreatedAt: entry.CreatedAt.Time,
+		UpdatedAt: entry.UpdatedAt.Time,
+	}
+	end := time.Now()
+	if entry.EndedAt.Valid {
+		resp.EndedAt = &entry.EndedAt.Time
+		end = entry.EndedAt.Time
+	}
+	resp.Seconds = int64(end.Sub(entry.StartedAt.Time).Seconds())
+	return resp
+}
+
+// writeStoreError maps errors returned by the stores to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Time entry not found", http.StatusNotFound)
+	case errors.Is(err, ErrNotOwner):
+		http.Error(w, "Only the owner can change a time entry", http.StatusForbidden)
+	case errors.Is(err, ErrTimerRunning):
+		http.Error(w, "A timer is already running, stop it first", http.StatusConflict)
+	case errors.Is(err, ErrTimerNotRunning):
+		http.Error(w, "No timer is running on this task", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+func entryID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.Atoi(r.PathValue("entryId"))
+	if err != nil {
+		http.Error(w, "Invalid time entry ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// parseReportFilter reads the from and to range, which defaults to the last
+// DefaultReportRange, and the optional userId, workspaceId and projectId filters.
+func parseReportFilter(r *http.Request) (ReportFilter, bool) {
+	query := r.URL.Query()
+
+	filter := ReportFilter{Until: time.Now()}
+	if value := query.Get("to"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return ReportFilter{}, false
+		}
+		filter.Until = until
+	}
+	filter.Since = filter.Until.Add(-DefaultReportRange)
+	if value := query.Get("from"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return ReportFilter{}, false
+		}
+		filter.Since = since
+	}
+	if !filter.Since.Before(filter.Until) || filter.Until.Sub(filter.Since) > MaxReportRange {
+		return ReportFilter{}, false
+	}
+
+	if value := query.Get("userId"); value != "" {
+		userID := r.Context().Value(jwt.UserContextKey).(uuid.UUID)
+		if value != "me" {
+			var err error
+			userID, err = uuid.Parse(value)
+			if err != nil {
+				return ReportFilter{}, false
+			}
+		}
+		filter.UserID = &userID
+	}
+
+	if value := query.Get("workspaceId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || id <= 0 {
+			return ReportFilter{}, false
+		}
+		filter.WorkspaceID = new(int32)
+		*filter.WorkspaceID = int32(id)
+	}
+
+	if value := query.Get("projectId"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || id <= 0 {
+			return ReportFilter{}, false
+		}
+		filter.ProjectID = new(int32)
+		*filter.ProjectID = int32(id)
+	}
+
+	return filter, true
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+
+	page, err := internal.ParsePagination(r)
+	if err != nil {
+		http.Error(w, "Invalid limit or offset", http.StatusBadRequest)
+		return
+	}
+
+	entries, total, err := h.store.List(ctx, taskID, page)
+	if err != nil {
+		h.logger.Error("Failed to list time entries", zap.Error(err))
+		http.Error(w, "Failed to get time entries", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListResponse{
+		Entries: make([]Response, len(entries)),
+		Total:   total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+	}
+	for i, entry := range entries {
+		resp.Entries[i] = newResponse(entry)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+
+	var req Request
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	entry, err := h.store.Create(ctx, userID, taskID, req.StartedAt, req.EndedAt, req.Note)
+	if err != nil {
+		h.logger.Error("Failed to create time entry", zap.Error(err))
+		writeStoreError(w, err, "Failed to create time entry")
+		return
+	}
+
+	resp := newResponse(entry)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+	id, ok := entryID(w, r)
+	if !ok {
+		return
+	}
+
+	var req Request
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	entry, err := h.store.Update(ctx, userID, taskID, id, req.StartedAt, req.EndedAt, req.Note)
+	if err != nil {
+		h.logger.Error("Failed to update time entry", zap.Error(err))
+		writeStoreError(w, err, "Failed to update time entry")
+		return
+	}
+
+	resp := newResponse(entry)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+	id, ok := entryID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, taskID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete time entry", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete time entry")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, true)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	entry, err := h.store.StartTimer(ctx, userID, taskID)
+	if err != nil {
+		h.logger.Error("Failed to start timer", zap.Error(err))
+		writeStoreError(w, err, "Failed to start timer")
+		return
+	}
+
+	resp := newResponse(entry)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Someone who lost the write role can still stop the timer they started
+	taskID, ok := task.PathTaskID(w, r, h.logger, h.taskStore, false)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	entry, err := h.store.StopTimer(ctx, userID, taskID)
+	if err != nil {
+		h.logger.Error("Failed to stop timer", zap.Error(err))
+		writeStoreError(w, err, "Failed to stop timer")
+		return
+	}
+
+	resp := newResponse(entry)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+// Report totals the time spent per task and user. Only tasks the caller can see are
+// included, but the time of every user on them is.
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, ok := parseReportFilter(r)
+	if !ok {
+		http.Error(w, "Invalid report filter", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	rows, err := h.store.Report(ctx, userID, filter)
+	if err != nil {
+		h.logger.Error("Failed to build time report", zap.Error(err))
+		http.Error(w, "Failed to build time report", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ReportResponse{
+		From: filter.Since,
+		To:   filter.Until,
+		Rows: make([]ReportRowResponse, len(rows)),
+	}
+	for i, row := range rows {
+		resp.TotalSeconds += row.Seconds
+		resp.Rows[i] = ReportRowResponse{
+			TaskID:    row.TaskID,
+			TaskTitle: row.TaskTitle,
+			UserID:    row.UserID.String(),
+			Username:  row.Username,
+			Seconds:   row.Seconds,
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,63 @@
+-- name: ListByTask :many
+SELECT sqlc.embed(e), u.username
+FROM time_entries e
+JOIN users u ON u.id = e.user_id
+WHERE e.task_id = $1
+ORDER BY e.started_at DESC, e.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountByTask :one
+SELECT count(*) FROM time_entries WHERE task_id = $1;
+
+-- name: GetByID :one
+SELECT sqlc.embed(e), u.username
+FROM time_entries e
+JOIN users u ON u.id = e.user_id
+WHERE e.id = $1 AND e.task_id = $2;
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM time_entries WHERE id = $1 AND task_id = $2 FOR UPDATE;
+
+-- name: Create :one
+INSERT INTO time_entries (task_id, user_id, started_at, ended_at, note)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: Update :one
+UPDATE time_entries
+SET started_at = $2, ended_at = $3, note = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM time_entries WHERE id = $1;
+
+-- name: StartTimer :one
+INSERT INTO time_entries (task_id, user_id, started_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+RETURNING *;
+
+-- name: StopTimer :one
+UPDATE time_entries
+SET ended_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE task_id = $1 AND user_id = $2 AND ended_at IS NULL
+RETURNING *;
+
+-- name: Report :many
+-- Sums the time spent on each task per user between from and to. Entries overlapping the
+-- range only count for the part inside it, and running timers count until now.
+SELECT e.task_id, t.title AS task_title, e.user_id, u.username,
+       sum(extract(epoch FROM least(COALESCE(e.ended_at, now()), sqlc.arg(until)::timestamptz) - greatest(e.started_at, sqlc.arg(since)::timestamptz)))::bigint AS seconds
+FROM time_entries e
+JOIN tasks t ON t.id = e.task_id
+JOIN users u ON u.id = e.user_id
+WHERE e.started_at < sqlc.arg(until)::timestamptz
+  AND COALESCE(e.ended_at, now()) > sqlc.arg(since)::timestamptz
+  AND t.deleted_at IS NULL
+  AND (t.workspace_id IS NULL OR t.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))
+  AND (sqlc.narg(user_id)::uuid IS NULL OR e.user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(workspace_id)::int IS NULL OR t.workspace_id = sqlc.narg(workspace_id))
+  AND (sqlc.narg(project_id)::int IS NULL OR t.project_id = sqlc.narg(project_id))
+  AND (sqlc.narg(project_id)::int IS NULL OR EXISTS (SELECT 1 FROM projects p WHERE p.id = sqlc.narg(project_id) AND p.owner_id = sqlc.arg(viewer_id)))
+GROUP BY e.task_id, t.title, e.user_id, u.username
+ORDER BY e.task_id ASC, u.username ASC;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS time_entries (
+    id SERIAL NOT NULL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMPTZ NOT NULL,
+    -- NULL while the timer is running
+    ended_at TIMESTAMPTZ,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+-- A user has at most one running timer
+CREATE UNIQUE INDEX IF NOT EXISTS time_entries_running_idx ON time_entries (user_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS time_entries_task_id_idx ON time_entries (task_id, started_at);
+CREATE INDEX IF NOT EXISTS time_entries_user_id_idx ON time_entries (user_id, started_at);
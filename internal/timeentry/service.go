@@ -0,0 +1,276 @@
+package timeentry
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("time entry not found")
+	// ErrNotOwner is returned when someone other than the user who logged the time changes an entry
+	ErrNotOwner = errors.New("only the owner can change a time entry")
+	// ErrTimerRunning is returned when starting a timer while another one is running
+	ErrTimerRunning    = errors.New("a timer is already running")
+	ErrTimerNotRunning = errors.New("no timer is running on this task")
+)
+
+// Entry is a time entry together with the username of the user who logged it.
+type Entry struct {
+	TimeEntry
+	Username string
+}
+
+// ReportFilter narrows a time report. Nil fields are not applied.
+type ReportFilter struct {
+	Since       time.Time
+	Until       time.Time
+	UserID      *uuid.UUID
+	WorkspaceID *int32
+	ProjectID   *int32
+}
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+func (s Service) List(ctx context.Context, taskID int32, page internal.Pagination) ([]Entry, int64, error) {
+	rows, err := s.queries.ListByTask(ctx, ListByTaskParams{
+		TaskID: taskID,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list time entries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	total, err := s.queries.CountByTask(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to count time entries", zap.Error(err))
+		return nil, 0, err
+	}
+
+	entries := make([]Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = Entry{
+			TimeEntry: row.TimeEntry,
+			Username:  row.Username,
+		}
+	}
+	return entries, total, nil
+}
+
+// Create logs time that was not tracked with a timer.
+func (s Service) Create(ctx context.Context, userID uuid.UUID, taskID int32, startedAt, endedAt time.Time, note string) (Entry, error) {
+	var entry Entry
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		created, err := q.Create(ctx, CreateParams{
+			TaskID:    taskID,
+			UserID:    userID,
+			StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+			EndedAt:   pgtype.Timestamptz{Time: endedAt, Valid: true},
+			Note:      note,
+		})
+		if err != nil {
+			return err
+		}
+
+		entry, err = getEntry(ctx, q, taskID, created.ID)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to create time entry", zap.Error(err))
+		return Entry{}, err
+	}
+
+	s.logger.Info("Created time entry", zap.Int32("task_id", taskID), zap.Int32("entry_id", entry.ID))
+	return entry, nil
+}
+
+// Update replaces the times and note of an entry. Setting the end of a running entry
+// stops its timer.
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, taskID, id int32, startedAt, endedAt time.Time, note string) (Entry, error) {
+	var entry Entry
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := lockForOwner(ctx, q, actorID, taskID, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Update(ctx, UpdateParams{
+			ID:        id,
+			StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+			EndedAt:   pgtype.Timestamptz{Time: endedAt, Valid: true},
+			Note:      note,
+		})
+		if err != nil {
+			return err
+		}
+
+		entry, err = getEntry(ctx, q, taskID, id)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to update time entry", err)
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, taskID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := lockForOwner(ctx, q, actorID, taskID, id)
+		if err != nil {
+			return err
+		}
+
+		return q.Delete(ctx, id)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete time entry", err)
+		return err
+	}
+
+	s.logger.Info("Deleted time entry", zap.Int32("task_id", taskID), zap.Int32("entry_id", id))
+	return nil
+}
+
+// StartTimer starts tracking time on the task. The database allows a single running
+// timer per user, so the one running elsewhere has to be stopped first.
+func (s Service) StartTimer(ctx context.Context, userID uuid.UUID, taskID int32) (Entry, error) {
+	var entry Entry
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		started, err := q.StartTimer(ctx, StartTimerParams{
+			TaskID: taskID,
+			UserID: userID,
+		})
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.ConstraintName == "time_entries_running_idx" {
+				return ErrTimerRunning
+			}
+			return err
+		}
+
+		entry, err = getEntry(ctx, q, taskID, started.ID)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to start timer", err)
+		return Entry{}, err
+	}
+
+	s.logger.Info("Started timer", zap.Int32("task_id", taskID), zap.String("user_id", userID.String()))
+	return entry, nil
+}
+
+// StopTimer stops the user's timer on the task.
+func (s Service) StopTimer(ctx context.Context, userID uuid.UUID, taskID int32) (Entry, error) {
+	var entry Entry
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		stopped, err := q.StopTimer(ctx, StopTimerParams{
+			TaskID: taskID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTimerNotRunning
+			}
+			return err
+		}
+
+		entry, err = getEntry(ctx, q, taskID, stopped.ID)
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to stop timer", err)
+		return Entry{}, err
+	}
+
+	s.logger.Info("Stopped timer", zap.Int32("task_id", taskID), zap.String("user_id", userID.String()))
+	return entry, nil
+}
+
+// Report sums the time spent per task and user within the filter's range, over the tasks
+// the viewer can see.
+func (s Service) Report(ctx context.Context, viewerID uuid.UUID, filter ReportFilter) ([]ReportRow, error) {
+	params := ReportParams{
+		Since:    pgtype.Timestamptz{Time: filter.Since, Valid: true},
+		Until:    pgtype.Timestamptz{Time: filter.Until, Valid: true},
+		ViewerID: viewerID,
+	}
+	if filter.UserID != nil {
+		params.UserID = pgtype.UUID{Bytes: *filter.UserID, Valid: true}
+	}
+	if filter.WorkspaceID != nil {
+		params.WorkspaceID = pgtype.Int4{Int32: *filter.WorkspaceID, Valid: true}
+	}
+	if filter.ProjectID != nil {
+		params.ProjectID = pgtype.Int4{Int32: *filter.ProjectID, Valid: true}
+	}
+
+	rows, err := s.queries.Report(ctx, params)
+	if err != nil {
+		s.logger.Error("Failed to build time report", zap.Error(err))
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err,
+		ErrNotFound, ErrNotOwner, ErrTimerRunning, ErrTimerNotRunning)
+}
+
+// lockForOwner locks the entry for the rest of the transaction and checks that the actor
+// logged it.
+func lockForOwner(ctx context.Context, q *Queries, actorID uuid.UUID, taskID, id int32) error {
+	entry, err := q.GetByIDForUpdate(ctx, GetByIDForUpdateParams{
+		ID:     id,
+		TaskID: taskID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if entry.UserID != actorID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+func getEntry(ctx context.Context, q *Queries, taskID, id int32) (Entry, error) {
+	row, err := q.GetByID(ctx, GetByIDParams{
+		ID:     id,
+		TaskID: taskID,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{
+		TimeEntry: row.TimeEntry,
+		Username:  row.Username,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner starts transactions. Both pgxpool.Pool and pgx.Tx are one, the latter
+// starting savepoints.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// TxQueries is implemented by the Queries that sqlc generates for each package.
+type TxQueries[Q any] interface {
+	WithTx(tx pgx.Tx) Q
+}
+
+// WithTx runs fn in a transaction of db, which is committed only if fn succeeds.
+func WithTx(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Rollback is a no-op once the transaction has been committed
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// WithTxQueries is WithTx for fn using queries bound to the transaction.
+func WithTxQueries[Q any](ctx context.Context, db TxBeginner, queries TxQueries[Q], fn func(q Q) error) error {
+	return WithTx(ctx, db, func(tx pgx.Tx) error {
+		return fn(queries.WithTx(tx))
+	})
+}
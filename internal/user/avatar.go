@@ -0,0 +1,110 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"slices"
+)
+
+const (
+	// MaxAvatarSize is the largest image accepted as an avatar, in bytes
+	MaxAvatarSize     = 5 << 20
+	DefaultAvatarSize = 128
+	// maxAvatarPixels keeps small but highly compressed images from exhausting memory. A
+	// decoded image of this size takes 64MB as RGBA, the square crop up to as much again.
+	maxAvatarPixels = 16_000_000
+)
+
+// AvatarSizes are the widths in pixels uploaded avatars are stored at. Avatars are square.
+var AvatarSizes = []int{64, 128, 256}
+
+var ErrInvalidImage = errors.New("image is not a valid PNG, JPEG or GIF")
+
+// resizeAvatar crops the image to a centered square and encodes it as PNG in each of
+// AvatarSizes.
+func resizeAvatar(data []byte) (map[int][]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !isAvatarDecodable(config) {
+		return nil, ErrInvalidImage
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	square := cropSquare(img)
+	resized := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+		err = png.Encode(&buf, scale(square, size))
+		if err != nil {
+			return nil, err
+		}
+		resized[size] = buf.Bytes()
+	}
+	return resized, nil
+}
+
+// isAvatarDecodable reports whether decoding an image of these dimensions stays within
+// maxAvatarPixels.
+func isAvatarDecodable(config image.Config) bool {
+	return config.Width > 0 && config.Height > 0 && config.Width*config.Height <= maxAvatarPixels
+}
+
+func isAvatarSize(size int) bool {
+	return slices.Contains(AvatarSizes, size)
+}
+
+// cropSquare copies the centered square of the image into premultiplied RGBA, which can
+// be averaged channel by channel.
+func cropSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// scale resizes a square image by averaging the source pixels each target pixel covers.
+// When enlarging, each target pixel covers a single source pixel.
+func scale(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0 := y * side / size
+		y1 := max((y+1)*side/size, y0+1)
+		for x := 0; x < size; x++ {
+			x0 := x * side / size
+			x1 := max((x+1)*side/size, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint64(pixel[0])
+					g += uint64(pixel[1])
+					b += uint64(pixel[2])
+					a += uint64(pixel[3])
+					n++
+				}
+			}
+
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,185 @@
+package user
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns a PNG that declares the given dimensions but holds no pixel data.
+func pngHeader(width, height uint32) []byte {
+	var ihdr [13]byte
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr[:]...)
+	buf.Write(chunk)
+	_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestIsAvatarDecodable(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          bool
+	}{
+		{1, 1, true},
+		{4000, 4000, true},
+		{maxAvatarPixels, 1, true},
+		{4000, 4001, false},
+		{1 << 30, 1 << 30, false},
+		{0, 100, false},
+		{100, 0, false},
+	}
+
+	for _, tt := range tests {
+		got := isAvatarDecodable(image.Config{Width: tt.width, Height: tt.height})
+		if got != tt.want {
+			t.Errorf("isAvatarDecodable(%dx%d) = %v, want %v", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestResizeAvatarRejectsOversizedImage(t *testing.T) {
+	// Decoding would allocate 256GB if the dimensions were not checked first
+	_, err := resizeAvatar(pngHeader(1<<18, 1<<18))
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("got %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestResizeAvatar(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resized, err := resizeAvatar(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resized) != len(AvatarSizes) {
+		t.Fatalf("got %d sizes, want %d", len(resized), len(AvatarSizes))
+	}
+	for _, size := range AvatarSizes {
+		config, err := png.DecodeConfig(bytes.NewReader(resized[size]))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if config.Width != size || config.Height != size {
+			t.Errorf("size %d: got %dx%d", size, config.Width, config.Height)
+		}
+	}
+
+	_, err = resizeAvatar([]byte("not an image"))
+	if !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("got %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestCropSquare(t *testing.T) {
+	// A 6x4 image whose left and right columns differ from the centered 4x4 square
+	img := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x == 0 || x == 5 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	square := cropSquare(img)
+	if square.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("got bounds %v, want 4x4 at the origin", square.Bounds())
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if got := square.RGBAAt(x, y); got != (color.RGBA{R: 0xff, A: 0xff}) {
+				t.Fatalf("pixel %d,%d = %v, want the centered red square", x, y, got)
+			}
+		}
+	}
+
+	// A portrait sub-image that does not start at the origin
+	tall := image.NewRGBA(image.Rect(10, 20, 13, 27))
+	tall.SetRGBA(11, 22, color.RGBA{G: 0xff, A: 0xff})
+	square = cropSquare(tall)
+	if square.Bounds() != image.Rect(0, 0, 3, 3) {
+		t.Fatalf("got bounds %v, want 3x3 at the origin", square.Bounds())
+	}
+	if got := square.RGBAAt(1, 0); got != (color.RGBA{G: 0xff, A: 0xff}) {
+		t.Errorf("pixel 1,0 = %v, want the green source pixel", got)
+	}
+}
+
+func TestScale(t *testing.T) {
+	// Left half black, right half white
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{A: 0xff}
+			if x >= 2 {
+				c = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	halved := scale(src, 2)
+	if halved.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("got bounds %v", halved.Bounds())
+	}
+	if got := halved.RGBAAt(0, 1); got != (color.RGBA{A: 0xff}) {
+		t.Errorf("left pixel = %v, want black", got)
+	}
+	if got := halved.RGBAAt(1, 1); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("right pixel = %v, want white", got)
+	}
+
+	// Each target pixel averages the source pixels it covers
+	single := scale(src, 1)
+	if got := single.RGBAAt(0, 0); got != (color.RGBA{R: 0x7f, G: 0x7f, B: 0x7f, A: 0xff}) {
+		t.Errorf("averaged pixel = %v, want mid grey", got)
+	}
+
+	doubled := scale(src, 8)
+	if doubled.Bounds() != image.Rect(0, 0, 8, 8) {
+		t.Fatalf("got bounds %v", doubled.Bounds())
+	}
+	if got := doubled.RGBAAt(3, 7); got != (color.RGBA{A: 0xff}) {
+		t.Errorf("enlarged pixel 3,7 = %v, want black", got)
+	}
+	if got := doubled.RGBAAt(4, 0); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("enlarged pixel 4,0 = %v, want white", got)
+	}
+}
+
+func TestIsAvatarSize(t *testing.T) {
+	for _, size := range AvatarSizes {
+		if !isAvatarSize(size) {
+			t.Errorf("isAvatarSize(%d) = false", size)
+		}
+	}
+	for _, size := range []int{0, 32, 100, 512} {
+		if isAvatarSize(size) {
+			t.Errorf("isAvatarSize(%d) = true", size)
+		}
+	}
+}
@@ -1,32 +1,101 @@
 package user
 
 import (
+	"advanced-backend/internal"
 	"advanced-backend/internal/jwt"
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 )
 
+// avatarCacheAge is how long clients may cache an avatar without revalidating it
+const avatarCacheAge = 24 * 60 * 60
+
 type Store interface {
 	Create(ctx context.Context, email, username, avatarURL string) (User, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	GetByID(ctx context.Context, id uuid.UUID) (User, error)
-	Update(ctx context.Context, id uuid.UUID, about string) (User, error)
+	Update(ctx context.Context, id uuid.UUID, fields ProfileFields) (User, error)
+	SetAvatar(ctx context.Context, id uuid.UUID, image []byte) (User, error)
+	DeleteAvatar(ctx context.Context, id uuid.UUID) (User, error)
+	GetAvatar(ctx context.Context, id uuid.UUID, size int) (User, io.ReadCloser, error)
 }
 
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Request is a JSON Merge Patch document for the caller's profile. Omitted members are
+// left unchanged and null clears the display name or about text.
 type Request struct {
-	About string `json:"about" validate:"max=500"`
+	Username      internal.Optional[string]               `json:"username"`
+	DisplayName   internal.Optional[string]               `json:"displayName"`
+	About         internal.Optional[string]               `json:"about"`
+	Timezone      internal.Optional[string]               `json:"timezone"`
+	Locale        internal.Optional[string]               `json:"locale"`
+	Notifications internal.Optional[NotificationsRequest] `json:"notifications"`
+}
+
+// NotificationsRequest patches the notification preferences. None of them can be null.
+type NotificationsRequest struct {
+	Mentions    internal.Optional[bool] `json:"mentions"`
+	Assignments internal.Optional[bool] `json:"assignments"`
+	Comments    internal.Optional[bool] `json:"comments"`
+	DueDates    internal.Optional[bool] `json:"dueDates"`
+}
+
+func (req NotificationsRequest) preferences() NotificationPreferences {
+	return NotificationPreferences{
+		Mentions:    optionalBool(req.Mentions),
+		Assignments: optionalBool(req.Assignments),
+		Comments:    optionalBool(req.Comments),
+		DueDates:    optionalBool(req.DueDates),
+	}
+}
+
+func optionalBool(o internal.Optional[bool]) *bool {
+	if !o.HasValue() {
+		return nil
+	}
+	return &o.Value
+}
+
+func validateRequest(sl validator.StructLevel) {
+	req := sl.Current().Interface().(Request)
+
+	internal.ValidateOptional(sl, req.Username, "username", "Username", false, "required,min=3,max=50,username")
+	internal.ValidateOptional(sl, req.DisplayName, "displayName", "DisplayName", true, "max=100")
+	internal.ValidateOptional(sl, req.About, "about", "About", true, "max=500")
+	internal.ValidateOptional(sl, req.Timezone, "timezone", "Timezone", false, "required,timezone")
+	internal.ValidateOptional(sl, req.Locale, "locale", "Locale", false, "required,bcp47_language_tag")
+	internal.ValidateOptional(sl, req.Notifications, "notifications", "Notifications", false, "")
+
+	notifications := req.Notifications.Value
+	internal.ValidateOptional(sl, notifications.Mentions, "notifications.mentions", "Notifications.Mentions", false, "")
+	internal.ValidateOptional(sl, notifications.Assignments, "notifications.assignments", "Notifications.Assignments", false, "")
+	internal.ValidateOptional(sl, notifications.Comments, "notifications.comments", "Notifications.Comments", false, "")
+	internal.ValidateOptional(sl, notifications.DueDates, "notifications.dueDates", "Notifications.DueDates", false, "")
+}
+
+func validateUsername(fl validator.FieldLevel) bool {
+	return usernamePattern.MatchString(fl.Field().String())
 }
 
 type Response struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	Username  string `json:"username"`
-	About     string `json:"about"`
-	AvatarURL string `json:"avatarUrl"`
+	ID            string                  `json:"id"`
+	Email         string                  `json:"email"`
+	Username      string                  `json:"username"`
+	DisplayName   string                  `json:"displayName"`
+	About         string                  `json:"about"`
+	AvatarURL     string                  `json:"avatarUrl"`
+	Timezone      string                  `json:"timezone"`
+	Locale        string                  `json:"locale"`
+	Notifications NotificationPreferences `json:"notifications"`
 }
 
 type Handler struct {
@@ -36,6 +105,12 @@ type Handler struct {
 }
 
 func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store) *Handler {
+	validator.RegisterStructValidation(validateRequest, Request{})
+	_ = internal.RegisterValidation(validator, "username", validateUsername,
+		"may only contain letters, digits, dots, dashes and underscores")
+	internal.RegisterRuleMessage("timezone", "must be an IANA time zone such as Europe/Paris")
+	internal.RegisterRuleMessage("bcp47_language_tag", "must be a BCP 47 language tag such as en-US")
+
 	return &Handler{
 		logger:    logger,
 		validator: validator,
@@ -55,13 +130,7 @@ func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := Response{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		Username:  user.Username,
-		About:     user.AboutMe.String,
-		AvatarURL: user.AvatarUrl.String,
-	}
+	resp := newResponse(user)
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(resp)
@@ -72,40 +141,51 @@ func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Update applies a JSON Merge Patch to the caller's profile. Changing the username to one
+// that is taken is a conflict.
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var req Request
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := internal.ParseMergePatch(h.validator, r, &req)
 	if err != nil {
-		h.logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	err = h.validator.Struct(req)
-	if err != nil {
-		h.logger.Error("Validation failed", zap.Error(err))
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
 		return
 	}
 
 	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
 
-	user, err := h.store.Update(ctx, userID, req.About)
+	fields := ProfileFields{
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		About:       req.About,
+		Timezone:    req.Timezone,
+		Locale:      req.Locale,
+	}
+	if req.Notifications.HasValue() {
+		fields.Notifications = internal.Optional[NotificationPreferences]{
+			Value: req.Notifications.Value.preferences(),
+			Set:   true,
+		}
+	}
+
+	user, err := h.store.Update(ctx, userID, fields)
 	if err != nil {
+		if errors.Is(err, ErrUsernameTaken) {
+			http.Error(w, "Username is already taken", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
 		h.logger.Error("Failed to update user", zap.Error(err))
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
 
-	resp := Response{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		Username:  user.Username,
-		About:     user.AboutMe.String,
-		AvatarURL: user.AvatarUrl.String,
-	}
+	resp := newResponse(user)
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(resp)
@@ -115,3 +195,148 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func newResponse(user User) Response {
+	resp := Response{
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		Username:    user.Username,
+		DisplayName: user.DisplayName.String,
+		About:       user.AboutMe.String,
+		AvatarURL:   user.AvatarUrl.String,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+	}
+	// The column default sets every preference, so decoding only fails on corrupt data
+	_ = json.Unmarshal(user.NotificationPreferences, &resp.Notifications)
+	return resp
+}
+
+// UploadAvatar replaces the caller's avatar with the image sent as the field file of a
+// multipart/form-data request.
+func (h *Handler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Leaves room for the multipart headers around the image
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAvatarSize+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Image is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.logger.Warn("Failed to read avatar upload", zap.Error(err))
+		http.Error(w, "Multipart field file is required", http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+		_ = r.MultipartForm.RemoveAll()
+	}()
+
+	image, err := io.ReadAll(io.LimitReader(file, MaxAvatarSize+1))
+	if err != nil {
+		h.logger.Warn("Failed to read avatar upload", zap.Error(err))
+		http.Error(w, "Failed to read image", http.StatusBadRequest)
+		return
+	}
+	if len(image) > MaxAvatarSize {
+		http.Error(w, "Image is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	user, err := h.store.SetAvatar(ctx, userID, image)
+	if err != nil {
+		if errors.Is(err, ErrInvalidImage) {
+			http.Error(w, "Image must be a PNG, JPEG or GIF", http.StatusUnsupportedMediaType)
+			return
+		}
+		h.logger.Error("Failed to set avatar", zap.Error(err))
+		http.Error(w, "Failed to set avatar", http.StatusInternalServerError)
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, newResponse(user))
+}
+
+// DeleteAvatar removes the caller's uploaded avatar, bringing back their OAuth picture.
+func (h *Handler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	user, err := h.store.DeleteAvatar(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to delete avatar", zap.Error(err))
+		http.Error(w, "Failed to delete avatar", http.StatusInternalServerError)
+		return
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, newResponse(user))
+}
+
+// GetAvatar serves the avatar of any user in one of AvatarSizes, chosen with ?size=. Users
+// without an uploaded avatar are redirected to their OAuth picture.
+func (h *Handler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	size := DefaultAvatarSize
+	if value := r.URL.Query().Get("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || !isAvatarSize(size) {
+			http.Error(w, "Invalid avatar size", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, content, err := h.store.GetAvatar(ctx, userID, size)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get avatar", zap.Error(err))
+		http.Error(w, "Failed to get avatar", http.StatusInternalServerError)
+		return
+	}
+
+	if content == nil {
+		if !user.OauthAvatarUrl.Valid || user.OauthAvatarUrl.String == "" {
+			http.Error(w, "User has no avatar", http.StatusNotFound)
+			return
+		}
+		// Not cached for long, since an upload replaces the target
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		http.Redirect(w, r, user.OauthAvatarUrl.String, http.StatusFound)
+		return
+	}
+	defer content.Close()
+
+	header := w.Header()
+	header.Set("ETag", strconv.Quote(user.AvatarKey.String+"/"+strconv.Itoa(size)))
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(avatarCacheAge))
+	if r.Header.Get("If-None-Match") == header.Get("ETag") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", "image/png")
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		h.logger.Warn("Failed to send avatar", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+}
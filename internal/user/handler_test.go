@@ -0,0 +1,123 @@
+package user
+
+import (
+	"advanced-backend/internal/jwt"
+	"context"
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeStore records the profile update it receives and returns err from it.
+type fakeStore struct {
+	Store
+	fields  ProfileFields
+	updated bool
+	err     error
+}
+
+func (s *fakeStore) Update(_ context.Context, id uuid.UUID, fields ProfileFields) (User, error) {
+	s.fields = fields
+	s.updated = true
+	if s.err != nil {
+		return User{}, s.err
+	}
+	return User{ID: id, NotificationPreferences: []byte(`{}`)}, nil
+}
+
+func patchProfile(store *fakeStore, contentType, body string) *httptest.ResponseRecorder {
+	handler := NewHandler(zap.NewNop(), validator.New(), store)
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/user/me", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r = r.WithContext(context.WithValue(r.Context(), jwt.UserContextKey, uuid.New()))
+
+	w := httptest.NewRecorder()
+	handler.Update(w, r)
+	return w
+}
+
+func TestUpdateRequiresMergePatch(t *testing.T) {
+	store := &fakeStore{}
+	w := patchProfile(store, "application/json", `{"about":"hi"}`)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("got status %d, want 415", w.Code)
+	}
+	if got := w.Header().Get("Accept-Patch"); got != "application/merge-patch+json" {
+		t.Errorf("got Accept-Patch %q", got)
+	}
+	if store.updated {
+		t.Error("the profile was updated")
+	}
+}
+
+func TestUpdateRejectsInvalidNotifications(t *testing.T) {
+	tests := []struct {
+		body  string
+		field string
+		rule  string
+	}{
+		{`{"notifications":null}`, "notifications", "notnull"},
+		{`{"notifications":{"mentions":null}}`, "notifications.mentions", "notnull"},
+		{`{"notifications":{"mentions":true,"digest":true}}`, "digest", "unknown"},
+	}
+
+	for _, tt := range tests {
+		store := &fakeStore{}
+		w := patchProfile(store, "application/merge-patch+json", tt.body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", tt.body, w.Code)
+			continue
+		}
+
+		var resp struct {
+			Errors []struct {
+				Field string `json:"field"`
+				Rule  string `json:"rule"`
+			} `json:"errors"`
+		}
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		if len(resp.Errors) != 1 || resp.Errors[0].Field != tt.field || resp.Errors[0].Rule != tt.rule {
+			t.Errorf("%s: got errors %+v, want %s %s", tt.body, resp.Errors, tt.field, tt.rule)
+		}
+		if store.updated {
+			t.Errorf("%s: the profile was updated", tt.body)
+		}
+	}
+}
+
+func TestUpdateNotifications(t *testing.T) {
+	store := &fakeStore{}
+	w := patchProfile(store, "application/merge-patch+json", `{"notifications":{"mentions":false}}`)
+	if w.Code != http.StatusOK {
+		body, _ := io.ReadAll(w.Body)
+		t.Fatalf("got status %d: %s", w.Code, body)
+	}
+
+	notifications := store.fields.Notifications
+	if !notifications.HasValue() {
+		t.Fatal("notifications were not passed to the store")
+	}
+	if notifications.Value.Mentions == nil || *notifications.Value.Mentions {
+		t.Errorf("got mentions %v, want false", notifications.Value.Mentions)
+	}
+	if notifications.Value.Comments != nil {
+		t.Error("an omitted preference was set")
+	}
+}
+
+func TestUpdateUserNotFound(t *testing.T) {
+	store := &fakeStore{err: ErrNotFound}
+	w := patchProfile(store, "application/merge-patch+json", `{"about":"hi"}`)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want 404", w.Code)
+	}
+}
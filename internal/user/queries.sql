@@ -5,13 +5,43 @@ SELECT EXISTS (SELECT 1 FROM users WHERE id = $1) AS exists;
 SELECT EXISTS (SELECT 1 FROM users WHERE email = $1) AS exists;
 
 -- name: Create :one
-INSERT INTO users (email, username, avatar_url) VALUES ($1, $2, $3) RETURNING *;
+INSERT INTO users (email, username, avatar_url, oauth_avatar_url) VALUES ($1, $2, $3, $3) RETURNING *;
 
--- name: UpdateAbout :one
-UPDATE users SET about_me = $2 WHERE id = $1 RETURNING *;
+-- name: UpdateProfile :one
+-- Notification preferences are merged, so that a patch only changes the preferences it sets
+UPDATE users
+SET username     = COALESCE(sqlc.narg(username), username),
+    display_name = CASE WHEN sqlc.arg(set_display_name)::bool THEN sqlc.narg(display_name) ELSE display_name END,
+    about_me     = CASE WHEN sqlc.arg(set_about_me)::bool THEN sqlc.narg(about_me) ELSE about_me END,
+    timezone     = COALESCE(sqlc.narg(timezone), timezone),
+    locale       = COALESCE(sqlc.narg(locale), locale),
+    notification_preferences = notification_preferences || COALESCE(sqlc.narg(notification_preferences)::jsonb, '{}'::jsonb)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetAvatar :one
+-- A null key removes the uploaded avatar, which brings back the OAuth picture. Returns the
+-- key of the avatar that was replaced.
+UPDATE users u
+SET avatar_key = sqlc.narg(avatar_key),
+    avatar_url = CASE WHEN sqlc.narg(avatar_key)::text IS NULL THEN u.oauth_avatar_url ELSE sqlc.narg(avatar_url) END
+FROM (SELECT id, avatar_key FROM users WHERE id = sqlc.arg(id) FOR UPDATE) previous
+WHERE u.id = previous.id
+RETURNING previous.avatar_key AS previous_key;
 
 -- name: GetByID :one
 SELECT * FROM users WHERE id = $1;
 
 -- name: GetByEmail :one
-SELECT * FROM users WHERE email = $1;
\ No newline at end of file
+SELECT * FROM users WHERE email = $1;
+
+-- name: AcceptPendingInvitations :execrows
+WITH accepted AS (
+    UPDATE workspace_invitations
+    SET accepted_at = now()
+    WHERE email = lower(sqlc.arg(email)::text) AND accepted_at IS NULL AND expires_at > now()
+    RETURNING workspace_id, role
+)
+INSERT INTO workspace_members (workspace_id, user_id, role)
+SELECT workspace_id, sqlc.arg(user_id), role FROM accepted
+ON CONFLICT (workspace_id, user_id) DO NOTHING;
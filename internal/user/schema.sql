@@ -2,7 +2,19 @@ CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     username VARCHAR(50) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
+    -- The uploaded avatar when there is one, otherwise oauth_avatar_url
     avatar_url VARCHAR(512),
     about_me TEXT,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
-);
\ No newline at end of file
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Picture of the OAuth account
+    oauth_avatar_url VARCHAR(512),
+    -- Blob store prefix of the uploaded avatar, see user.AvatarSizes
+    avatar_key TEXT,
+    display_name VARCHAR(100),
+    -- IANA time zone name
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    -- BCP 47 language tag
+    locale TEXT NOT NULL DEFAULT 'en',
+    -- See user.NotificationPreferences
+    notification_preferences JSONB NOT NULL DEFAULT '{"mentions": true, "assignments": true, "comments": true, "dueDates": true}'
+);
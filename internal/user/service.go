@@ -1,21 +1,60 @@
 package user
 
 import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/storage"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
+	"io"
 )
 
+var (
+	ErrNotFound = errors.New("user not found")
+	// ErrUsernameTaken is returned when changing the username to one used by someone else
+	ErrUsernameTaken = errors.New("username is already taken")
+)
+
+// NotificationPreferences controls which events the user is notified about. Nil fields
+// are left unchanged by Update.
+type NotificationPreferences struct {
+	Mentions    *bool `json:"mentions,omitempty"`
+	Assignments *bool `json:"assignments,omitempty"`
+	Comments    *bool `json:"comments,omitempty"`
+	DueDates    *bool `json:"dueDates,omitempty"`
+}
+
+// ProfileFields holds the profile fields changed by Update. Fields that are not set are
+// left as they are, and a null display name or about text clears it.
+type ProfileFields struct {
+	Username      internal.Optional[string]
+	DisplayName   internal.Optional[string]
+	About         internal.Optional[string]
+	Timezone      internal.Optional[string]
+	Locale        internal.Optional[string]
+	Notifications internal.Optional[NotificationPreferences]
+}
+
 type Service struct {
 	logger  *zap.Logger
 	queries *Queries
+	blobs   storage.BlobStore
+	baseURL string
 }
 
-func NewService(logger *zap.Logger, db DBTX) *Service {
+func NewService(logger *zap.Logger, db DBTX, blobs storage.BlobStore, baseURL string) *Service {
 	return &Service{
 		logger:  logger,
 		queries: New(db),
+		blobs:   blobs,
+		baseURL: baseURL,
 	}
 }
 
@@ -38,6 +77,7 @@ func (s *Service) FindOrCreate(ctx context.Context, email, username, avatarURL s
 		}
 
 		s.logger.Info("Created user", zap.String("user_id", user.ID.String()), zap.String("email", user.Email))
+		s.acceptPendingInvitations(ctx, user)
 		return user, nil
 	}
 
@@ -48,9 +88,27 @@ func (s *Service) FindOrCreate(ctx context.Context, email, username, avatarURL s
 	}
 
 	s.logger.Info("Found existing user", zap.String("user_id", user.ID.String()), zap.String("email", user.Email))
+	s.acceptPendingInvitations(ctx, user)
 	return user, nil
 }
 
+// acceptPendingInvitations joins the user to every workspace that invited their email.
+// Failures are logged only, so that a broken invitation never blocks a login.
+func (s *Service) acceptPendingInvitations(ctx context.Context, user User) {
+	accepted, err := s.queries.AcceptPendingInvitations(ctx, AcceptPendingInvitationsParams{
+		Email:  user.Email,
+		UserID: user.ID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to accept pending workspace invitations", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return
+	}
+
+	if accepted > 0 {
+		s.logger.Info("Accepted pending workspace invitations", zap.String("user_id", user.ID.String()), zap.Int64("count", accepted))
+	}
+}
+
 func (s *Service) Create(ctx context.Context, email, username, avatarURL string) (User, error) {
 	newUser, err := s.queries.Create(ctx, CreateParams{
 		Email:     email,
@@ -98,16 +156,142 @@ func (s *Service) ExistsByEmail(ctx context.Context, email string) (bool, error)
 	return exists, nil
 }
 
-func (s *Service) Update(ctx context.Context, userID uuid.UUID, about string) (User, error) {
-	updatedUser, err := s.queries.UpdateAbout(ctx, UpdateAboutParams{
-		ID:      userID,
-		AboutMe: pgtype.Text{String: about, Valid: true},
+func (s *Service) Update(ctx context.Context, userID uuid.UUID, fields ProfileFields) (User, error) {
+	var notifications []byte
+	if fields.Notifications.HasValue() {
+		var err error
+		notifications, err = json.Marshal(fields.Notifications.Value)
+		if err != nil {
+			return User{}, err
+		}
+	}
+
+	updatedUser, err := s.queries.UpdateProfile(ctx, UpdateProfileParams{
+		Username:                optionalText(fields.Username),
+		SetDisplayName:          fields.DisplayName.Set,
+		DisplayName:             optionalText(fields.DisplayName),
+		SetAboutMe:              fields.About.Set,
+		AboutMe:                 optionalText(fields.About),
+		Timezone:                optionalText(fields.Timezone),
+		Locale:                  optionalText(fields.Locale),
+		NotificationPreferences: notifications,
+		ID:                      userID,
 	})
 	if err != nil {
-		s.logger.Error("Failed to update user about", zap.Error(err))
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "users_username_key" {
+			s.logger.Info("Failed to update user profile", zap.Error(err))
+			return User{}, ErrUsernameTaken
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return User{}, ErrNotFound
+		}
+		s.logger.Error("Failed to update user profile", zap.Error(err))
 		return User{}, err
 	}
 
-	s.logger.Info("Updated user about", zap.String("user_id", updatedUser.ID.String()), zap.String("about", updatedUser.AboutMe.String))
+	s.logger.Info("Updated user profile", zap.String("user_id", updatedUser.ID.String()))
 	return updatedUser, nil
 }
+
+func optionalText(o internal.Optional[string]) pgtype.Text {
+	return pgtype.Text{String: o.Value, Valid: o.HasValue()}
+}
+
+// SetAvatar resizes the image to AvatarSizes and makes it the user's avatar. Every upload
+// gets new keys and a new URL, so that cached copies of the previous avatar are not shown.
+func (s *Service) SetAvatar(ctx context.Context, userID uuid.UUID, image []byte) (User, error) {
+	resized, err := resizeAvatar(image)
+	if err != nil {
+		s.logger.Info("Failed to resize avatar", zap.String("user_id", userID.String()), zap.Error(err))
+		return User{}, err
+	}
+
+	version := uuid.New().String()
+	key := fmt.Sprintf("avatars/%s/%s", userID, version)
+	for _, size := range AvatarSizes {
+		err = s.blobs.Put(ctx, avatarBlobKey(key, size), bytes.NewReader(resized[size]), int64(len(resized[size])), "image/png")
+		if err != nil {
+			s.logger.Error("Failed to store avatar", zap.String("user_id", userID.String()), zap.Error(err))
+			s.deleteAvatarBlobs(context.WithoutCancel(ctx), key)
+			return User{}, err
+		}
+	}
+
+	return s.replaceAvatar(ctx, userID, pgtype.Text{String: key, Valid: true},
+		pgtype.Text{String: fmt.Sprintf("%s/api/users/%s/avatar?v=%s", s.baseURL, userID, version), Valid: true})
+}
+
+// DeleteAvatar removes the uploaded avatar, so that the OAuth picture is shown again.
+func (s *Service) DeleteAvatar(ctx context.Context, userID uuid.UUID) (User, error) {
+	return s.replaceAvatar(ctx, userID, pgtype.Text{}, pgtype.Text{})
+}
+
+// GetAvatar opens the uploaded avatar in the given size. The content is nil when the user
+// has not uploaded an avatar. The caller closes the content.
+func (s *Service) GetAvatar(ctx context.Context, userID uuid.UUID, size int) (User, io.ReadCloser, error) {
+	user, err := s.queries.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return User{}, nil, ErrNotFound
+		}
+		s.logger.Error("Failed to get user by ID", zap.Error(err))
+		return User{}, nil, err
+	}
+	if !user.AvatarKey.Valid {
+		return user, nil, nil
+	}
+
+	content, err := s.blobs.Get(ctx, avatarBlobKey(user.AvatarKey.String, size))
+	if err != nil {
+		s.logger.Error("Failed to open avatar", zap.String("user_id", userID.String()), zap.Error(err))
+		return User{}, nil, err
+	}
+	return user, content, nil
+}
+
+func (s *Service) replaceAvatar(ctx context.Context, userID uuid.UUID, key, url pgtype.Text) (User, error) {
+	previous, err := s.queries.SetAvatar(ctx, SetAvatarParams{
+		AvatarKey: key,
+		AvatarUrl: url,
+		ID:        userID,
+	})
+	if err != nil {
+		if key.Valid {
+			s.deleteAvatarBlobs(context.WithoutCancel(ctx), key.String)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return User{}, ErrNotFound
+		}
+		s.logger.Error("Failed to update avatar", zap.String("user_id", userID.String()), zap.Error(err))
+		return User{}, err
+	}
+
+	if previous.Valid {
+		s.deleteAvatarBlobs(ctx, previous.String)
+	}
+
+	user, err := s.queries.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get user by ID", zap.Error(err))
+		return User{}, err
+	}
+
+	s.logger.Info("Updated avatar", zap.String("user_id", userID.String()), zap.Bool("uploaded", key.Valid))
+	return user, nil
+}
+
+// deleteAvatarBlobs removes the stored sizes of an avatar. Failures only leave orphaned
+// blobs behind, so they are logged rather than returned.
+func (s *Service) deleteAvatarBlobs(ctx context.Context, key string) {
+	for _, size := range AvatarSizes {
+		err := s.blobs.Delete(ctx, avatarBlobKey(key, size))
+		if err != nil {
+			s.logger.Error("Failed to delete avatar", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func avatarBlobKey(key string, size int) string {
+	return fmt.Sprintf("%s/%d.png", key, size)
+}
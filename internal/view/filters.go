@@ -0,0 +1,57 @@
+package view
+
+import (
+	"advanced-backend/internal/task"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Filters is the task list definition stored with a view. It mirrors task.Filter, except
+// that the assignee can be "me" so that a shared view lists the tasks of whoever runs it.
+type Filters struct {
+	ProjectID      *int32               `json:"projectId,omitempty" validate:"omitempty,gt=0"`
+	Status         *string              `json:"status,omitempty" validate:"omitempty,max=50"`
+	StatusCategory *task.StatusCategory `json:"statusCategory,omitempty" validate:"omitempty,oneof=TODO DOING DONE"`
+	Priority       *task.TaskPriority   `json:"priority,omitempty" validate:"omitempty,oneof=P0 P1 P2 P3"`
+	Labels         []string             `json:"labels,omitempty" validate:"omitempty,max=20,dive,max=50"`
+	DueAfter       *time.Time           `json:"dueAfter,omitempty"`
+	DueBefore      *time.Time           `json:"dueBefore,omitempty"`
+	Overdue        bool                 `json:"overdue,omitempty"`
+	Assignee       string               `json:"assignee,omitempty" validate:"omitempty,uuid|eq=me"`
+	Query          string               `json:"query,omitempty" validate:"omitempty,max=200"`
+	Sort           string               `json:"sort,omitempty" validate:"omitempty,oneof=priority -priority dueDate -dueDate estimate -estimate timeSpent -timeSpent createdAt -createdAt"`
+}
+
+// TaskFilter resolves the filters for the viewer running the view. It fails when the
+// stored assignee is not a user ID, which validation prevents when the view is saved.
+func (f Filters) TaskFilter(view SavedView, viewerID uuid.UUID) (task.Filter, error) {
+	filter := task.Filter{
+		ProjectID:      f.ProjectID,
+		Status:         f.Status,
+		StatusCategory: f.StatusCategory,
+		Priority:       f.Priority,
+		Labels:         f.Labels,
+		DueAfter:       f.DueAfter,
+		DueBefore:      f.DueBefore,
+		Overdue:        f.Overdue,
+		Query:          f.Query,
+		Sort:           f.Sort,
+	}
+	if view.WorkspaceID.Valid {
+		filter.WorkspaceID = &view.WorkspaceID.Int32
+	}
+
+	switch f.Assignee {
+	case "":
+	case "me":
+		filter.AssigneeID = &viewerID
+	default:
+		assigneeID, err := uuid.Parse(f.Assignee)
+		if err != nil {
+			return task.Filter{}, fmt.Errorf("invalid assignee %q: %w", f.Assignee, err)
+		}
+		filter.AssigneeID = &assigneeID
+	}
+	return filter, nil
+}
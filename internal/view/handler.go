@@ -0,0 +1,346 @@
+package view
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"advanced-backend/internal/task"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Response struct {
+	ID          int32     `json:"id"`
+	OwnerID     string    `json:"ownerId"`
+	WorkspaceID *int32    `json:"workspaceId"`
+	Name        string    `json:"name"`
+	Filters     Filters   `json:"filters"`
+	Shared      bool      `json:"shared"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type CreateRequest struct {
+	Name        string  `json:"name" validate:"required,max=100"`
+	WorkspaceID *int32  `json:"workspaceId" validate:"omitempty,gt=0"`
+	Filters     Filters `json:"filters"`
+	Shared      bool    `json:"shared"`
+}
+
+type UpdateRequest struct {
+	Name    string  `json:"name" validate:"required,max=100"`
+	Filters Filters `json:"filters"`
+	Shared  bool    `json:"shared"`
+}
+
+type Store interface {
+	List(ctx context.Context, viewerID uuid.UUID) ([]SavedView, error)
+	GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (SavedView, error)
+	Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, name string, filters Filters, shared bool) (SavedView, error)
+	Update(ctx context.Context, actorID uuid.UUID, id int32, name string, filters Filters, shared bool) (SavedView, error)
+	Delete(ctx context.Context, actorID uuid.UUID, id int32) error
+}
+
+// taskStore runs the view through the regular task list.
+type taskStore interface {
+	GetAll(ctx context.Context, viewerID uuid.UUID, filter task.Filter) ([]task.Task, error)
+	GetDetails(ctx context.Context, taskIDs ...int32) (map[int32]task.Details, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	validator *validator.Validate
+	store     Store
+	taskStore taskStore
+}
+
+func NewHandler(logger *zap.Logger, validator *validator.Validate, store Store, taskStore taskStore) *Handler {
+	internal.RegisterRuleMessage("uuid|eq=me", `must be a user ID or "me"`)
+
+	return &Handler{
+		logger:    logger,
+		validator: validator,
+		store:     store,
+		taskStore: taskStore,
+	}
+}
+
+func decodeFilters(view SavedView) (Filters, error) {
+	var filters Filters
+	err := json.Unmarshal(view.Filters, &filters)
+	return filters, err
+}
+
+func newResponse(view SavedView) (Response, error) {
+	filters, err := decodeFilters(view)
+	if err != nil {
+		return Response{}, err
+	}
+
+	resp := Response{
+		ID:        view.ID,
+		OwnerID:   view.OwnerID.String(),
+		Name:      view.Name,
+		Filters:   filters,
+		Shared:    view.Shared,
+		CreatedAt: view.CreatedAt.Time,
+		UpdatedAt: view.UpdatedAt.Time,
+	}
+	if view.WorkspaceID.Valid {
+		resp.WorkspaceID = &view.WorkspaceID.Int32
+	}
+	return resp, nil
+}
+
+// writeStoreError maps errors returned by the store to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "View not found", http.StatusNotFound)
+	case errors.Is(err, ErrWorkspaceNotFound):
+		http.Error(w, "Workspace not found", http.StatusBadRequest)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Only the owner can change a view", http.StatusForbidden)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// pathViewID extracts the view ID from the URL, writing a 400 response when it is invalid.
+func pathViewID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "View ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid view ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// viewName trims the name, writing a 400 response when nothing is left.
+func viewName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		http.Error(w, "View name is required", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// GetAll lists the caller's views and the views shared with them.
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	views, err := h.store.List(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get views", zap.Error(err))
+		writeStoreError(w, err, "Failed to get views")
+		return
+	}
+
+	resp := make([]Response, len(views))
+	for i, view := range views {
+		resp[i], err = newResponse(view)
+		if err != nil {
+			h.logger.Error("Failed to decode view filters", zap.Error(err))
+			http.Error(w, "Failed to get views", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathViewID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	view, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get view by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get view")
+		return
+	}
+
+	resp, err := newResponse(view)
+	if err != nil {
+		h.logger.Error("Failed to decode view filters", zap.Error(err))
+		http.Error(w, "Failed to get view", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := viewName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	view, err := h.store.Create(ctx, userID, req.WorkspaceID, name, req.Filters, req.Shared)
+	if err != nil {
+		h.logger.Error("Failed to create view", zap.Error(err))
+		writeStoreError(w, err, "Failed to create view")
+		return
+	}
+
+	resp, err := newResponse(view)
+	if err != nil {
+		h.logger.Error("Failed to decode view filters", zap.Error(err))
+		http.Error(w, "Failed to create view", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathViewID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	name, ok := viewName(w, req.Name)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	view, err := h.store.Update(ctx, userID, id, name, req.Filters, req.Shared)
+	if err != nil {
+		h.logger.Error("Failed to update view", zap.Error(err))
+		writeStoreError(w, err, "Failed to update view")
+		return
+	}
+
+	resp, err := newResponse(view)
+	if err != nil {
+		h.logger.Error("Failed to decode view filters", zap.Error(err))
+		http.Error(w, "Failed to update view", http.StatusInternalServerError)
+		return
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathViewID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete view", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete view")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTasks runs the view for the caller. Tasks are listed as by GET /api/task, so the
+// caller only sees tasks they have access to, whoever shared the view.
+func (h *Handler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathViewID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	view, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Warn("Failed to get view by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get view")
+		return
+	}
+
+	filters, err := decodeFilters(view)
+	if err != nil {
+		h.logger.Error("Failed to decode view filters", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := filters.TaskFilter(view, userID)
+	if err != nil {
+		h.logger.Error("Failed to resolve view filters", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	tasks, err := h.taskStore.GetAll(ctx, userID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get view tasks", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int32, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	details, err := h.taskStore.GetDetails(ctx, ids...)
+	if err != nil {
+		h.logger.Error("Failed to get task details", zap.Error(err))
+		http.Error(w, "Failed to get tasks", http.StatusInternalServerError)
+		return
+	}
+
+	resp := task.NewResponses(tasks, details)
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
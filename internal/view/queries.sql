@@ -0,0 +1,31 @@
+-- name: List :many
+SELECT * FROM saved_views
+WHERE owner_id = sqlc.arg(viewer_id)
+   OR (shared AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id))))
+ORDER BY lower(name) ASC, id ASC;
+
+-- name: GetByID :one
+SELECT * FROM saved_views
+WHERE id = sqlc.arg(id)
+  AND (owner_id = sqlc.arg(viewer_id)
+   OR (shared AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = sqlc.arg(viewer_id)))));
+
+-- name: GetByIDForUpdate :one
+SELECT * FROM saved_views WHERE id = $1 FOR UPDATE;
+
+-- name: Create :one
+INSERT INTO saved_views (owner_id, workspace_id, name, filters, shared)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: Update :one
+UPDATE saved_views
+SET name = $2, filters = $3, shared = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM saved_views WHERE id = $1;
+
+-- name: IsWorkspaceMember :one
+SELECT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2) AS exists;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL NOT NULL PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- The view only lists tasks of this workspace when set
+    workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Task list filters, see view.Filters
+    filters JSONB NOT NULL DEFAULT '{}',
+    -- Shared views are visible to the members of the workspace, or to everyone outside workspaces
+    shared BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS saved_views_owner_id_idx ON saved_views (owner_id);
+CREATE INDEX IF NOT EXISTS saved_views_workspace_id_idx ON saved_views (workspace_id) WHERE shared;
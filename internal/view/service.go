@@ -0,0 +1,176 @@
+package view
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound          = errors.New("view not found")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	// ErrForbidden is returned when changing a view shared by someone else
+	ErrForbidden = errors.New("view is owned by another user")
+)
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+// List returns the viewer's own views along with the views shared with them.
+func (s Service) List(ctx context.Context, viewerID uuid.UUID) ([]SavedView, error) {
+	views, err := s.queries.List(ctx, viewerID)
+	if err != nil {
+		s.logger.Error("Failed to list views", zap.Error(err))
+		return nil, err
+	}
+	return views, nil
+}
+
+func (s Service) GetByID(ctx context.Context, viewerID uuid.UUID, id int32) (SavedView, error) {
+	view, err := s.queries.GetByID(ctx, GetByIDParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SavedView{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get view by ID", zap.Error(err))
+		return SavedView{}, err
+	}
+	return view, nil
+}
+
+// Create saves a view. A view in a workspace is limited to its tasks and can only be
+// created by its members.
+func (s Service) Create(ctx context.Context, actorID uuid.UUID, workspaceID *int32, name string, filters Filters, shared bool) (SavedView, error) {
+	workspaceParam := pgtype.Int4{}
+	if workspaceID != nil {
+		workspaceParam = pgtype.Int4{Int32: *workspaceID, Valid: true}
+
+		member, err := s.queries.IsWorkspaceMember(ctx, IsWorkspaceMemberParams{
+			WorkspaceID: *workspaceID,
+			UserID:      actorID,
+		})
+		if err != nil {
+			s.logger.Error("Failed to check workspace membership", zap.Error(err))
+			return SavedView{}, err
+		}
+		if !member {
+			return SavedView{}, ErrWorkspaceNotFound
+		}
+	}
+
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return SavedView{}, err
+	}
+
+	view, err := s.queries.Create(ctx, CreateParams{
+		OwnerID:     actorID,
+		WorkspaceID: workspaceParam,
+		Name:        name,
+		Filters:     encoded,
+		Shared:      shared,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create view", zap.Error(err))
+		return SavedView{}, err
+	}
+
+	s.logger.Info("Created view", zap.Int32("view_id", view.ID), zap.String("owner_id", actorID.String()))
+	return view, nil
+}
+
+func (s Service) Update(ctx context.Context, actorID uuid.UUID, id int32, name string, filters Filters, shared bool) (SavedView, error) {
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return SavedView{}, err
+	}
+
+	var view SavedView
+	err = internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+
+		view, err = q.Update(ctx, UpdateParams{
+			ID:      id,
+			Name:    name,
+			Filters: encoded,
+			Shared:  shared,
+		})
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to update view", err)
+		return SavedView{}, err
+	}
+	return view, nil
+}
+
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, id int32) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		_, err := lockForOwner(ctx, q, actorID, id)
+		if err != nil {
+			return err
+		}
+		return q.Delete(ctx, id)
+	})
+	if err != nil {
+		s.logWriteError("Failed to delete view", err)
+		return err
+	}
+
+	s.logger.Info("Deleted view", zap.Int32("view_id", id))
+	return nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err, ErrNotFound, ErrForbidden)
+}
+
+// lockForOwner locks the view for the rest of the transaction. Views shared with the actor
+// are reported as forbidden, and other views as not found.
+func lockForOwner(ctx context.Context, q *Queries, actorID uuid.UUID, id int32) (SavedView, error) {
+	_, err := q.GetByID(ctx, GetByIDParams{
+		ID:       id,
+		ViewerID: actorID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SavedView{}, ErrNotFound
+		}
+		return SavedView{}, err
+	}
+
+	view, err := q.GetByIDForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SavedView{}, ErrNotFound
+		}
+		return SavedView{}, err
+	}
+	if view.OwnerID != actorID {
+		return SavedView{}, ErrForbidden
+	}
+	return view, nil
+}
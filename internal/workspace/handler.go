@@ -0,0 +1,488 @@
+package workspace
+
+import (
+	"advanced-backend/internal"
+	"advanced-backend/internal/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Response struct {
+	ID        int32         `json:"id"`
+	Name      string        `json:"name"`
+	CreatedBy string        `json:"createdBy"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Role      WorkspaceRole `json:"role"`
+}
+
+type MemberResponse struct {
+	UserID    string        `json:"userId"`
+	Username  string        `json:"username"`
+	Email     string        `json:"email"`
+	AvatarURL string        `json:"avatarUrl"`
+	Role      WorkspaceRole `json:"role"`
+	JoinedAt  time.Time     `json:"joinedAt"`
+}
+
+type InvitationResponse struct {
+	ID          string        `json:"id"`
+	WorkspaceID int32         `json:"workspaceId"`
+	Email       string        `json:"email"`
+	Role        WorkspaceRole `json:"role"`
+	InvitedBy   string        `json:"invitedBy"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	ExpiresAt   time.Time     `json:"expiresAt"`
+	AcceptURL   string        `json:"acceptUrl"`
+}
+
+type CreateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type UpdateRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type UpdateMemberRequest struct {
+	Role WorkspaceRole `json:"role" validate:"required,oneof=OWNER EDITOR VIEWER"`
+}
+
+type InviteRequest struct {
+	Email string        `json:"email" validate:"required,email,max=255"`
+	Role  WorkspaceRole `json:"role" validate:"omitempty,oneof=OWNER EDITOR VIEWER"`
+}
+
+type Store interface {
+	GetAll(ctx context.Context, userID uuid.UUID) ([]GetAllByMemberRow, error)
+	GetByID(ctx context.Context, userID uuid.UUID, id int32) (GetByIDForMemberRow, error)
+	Create(ctx context.Context, actorID uuid.UUID, name string) (Workspace, error)
+	Rename(ctx context.Context, actorID uuid.UUID, id int32, name string) (Workspace, error)
+	Delete(ctx context.Context, actorID uuid.UUID, id int32) error
+	ListMembers(ctx context.Context, actorID uuid.UUID, id int32) ([]ListMembersRow, error)
+	UpdateMemberRole(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID, role WorkspaceRole) error
+	RemoveMember(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) error
+	Invite(ctx context.Context, actorID uuid.UUID, id int32, email string, role WorkspaceRole) (WorkspaceInvitation, error)
+	ListInvitations(ctx context.Context, actorID uuid.UUID, id int32) ([]WorkspaceInvitation, error)
+	RevokeInvitation(ctx context.Context, actorID uuid.UUID, id int32, invitationID uuid.UUID) error
+	AcceptInvitation(ctx context.Context, actorID uuid.UUID, invitationID uuid.UUID) (GetByIDForMemberRow, error)
+}
+
+type Handler struct {
+	logger    *zap.Logger
+	baseURL   string
+	validator *validator.Validate
+	store     Store
+}
+
+func NewHandler(logger *zap.Logger, baseURL string, validator *validator.Validate, store Store) *Handler {
+	return &Handler{
+		logger:    logger,
+		baseURL:   baseURL,
+		validator: validator,
+		store:     store,
+	}
+}
+
+func (h *Handler) newInvitationResponse(invitation WorkspaceInvitation) InvitationResponse {
+	return InvitationResponse{
+		ID:          invitation.ID.String(),
+		WorkspaceID: invitation.WorkspaceID,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		InvitedBy:   invitation.InvitedBy.String(),
+		CreatedAt:   invitation.CreatedAt.Time,
+		ExpiresAt:   invitation.ExpiresAt.Time,
+		AcceptURL:   fmt.Sprintf("%s/api/invitations/%s/accept", h.baseURL, invitation.ID),
+	}
+}
+
+// writeStoreError maps errors returned by the store to an HTTP response.
+func writeStoreError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Insufficient workspace role", http.StatusForbidden)
+	case errors.Is(err, ErrMemberNotFound):
+		http.Error(w, "Workspace member not found", http.StatusNotFound)
+	case errors.Is(err, ErrLastOwner):
+		http.Error(w, "Workspace must keep at least one owner", http.StatusConflict)
+	case errors.Is(err, ErrInvitationNotFound):
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+	case errors.Is(err, ErrInvitationEmailMismatch):
+		http.Error(w, "Invitation was sent to a different email", http.StatusForbidden)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// pathWorkspaceID extracts the workspace ID from the URL, writing a 400 response when it is invalid.
+func pathWorkspaceID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	workspaces, err := h.store.GetAll(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get workspaces", zap.Error(err))
+		http.Error(w, "Failed to get workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	var resp = make([]Response, len(workspaces))
+	for i, workspace := range workspaces {
+		resp[i] = Response{
+			ID:        workspace.ID,
+			Name:      workspace.Name,
+			CreatedBy: workspace.CreatedBy.String(),
+			CreatedAt: workspace.CreatedAt.Time,
+			Role:      workspace.Role,
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	workspace, err := h.store.GetByID(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to get workspace by ID", zap.Error(err))
+		writeStoreError(w, err, "Failed to get workspace")
+		return
+	}
+
+	resp := Response{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		CreatedBy: workspace.CreatedBy.String(),
+		CreatedAt: workspace.CreatedAt.Time,
+		Role:      workspace.Role,
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	newWorkspace, err := h.store.Create(ctx, userID, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to create workspace", zap.Error(err))
+		http.Error(w, "Failed to create workspace", http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{
+		ID:        newWorkspace.ID,
+		Name:      newWorkspace.Name,
+		CreatedBy: newWorkspace.CreatedBy.String(),
+		CreatedAt: newWorkspace.CreatedAt.Time,
+		Role:      WorkspaceRoleOWNER,
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	updatedWorkspace, err := h.store.Rename(ctx, userID, id, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to rename workspace", zap.Error(err))
+		writeStoreError(w, err, "Failed to update workspace")
+		return
+	}
+
+	resp := Response{
+		ID:        updatedWorkspace.ID,
+		Name:      updatedWorkspace.Name,
+		CreatedBy: updatedWorkspace.CreatedBy.String(),
+		CreatedAt: updatedWorkspace.CreatedAt.Time,
+		Role:      WorkspaceRoleOWNER,
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err := h.store.Delete(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to delete workspace", zap.Error(err))
+		writeStoreError(w, err, "Failed to delete workspace")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	members, err := h.store.ListMembers(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to list workspace members", zap.Error(err))
+		writeStoreError(w, err, "Failed to get workspace members")
+		return
+	}
+
+	var resp = make([]MemberResponse, len(members))
+	for i, member := range members {
+		resp[i] = MemberResponse{
+			UserID:    member.UserID.String(),
+			Username:  member.Username,
+			Email:     member.Email,
+			AvatarURL: member.AvatarUrl.String,
+			Role:      member.Role,
+			JoinedAt:  member.JoinedAt.Time,
+		}
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateMemberRequest
+	err = internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.UpdateMemberRole(ctx, userID, id, memberID, req.Role)
+	if err != nil {
+		h.logger.Error("Failed to update workspace member", zap.Error(err))
+		writeStoreError(w, err, "Failed to update workspace member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.RemoveMember(ctx, userID, id, memberID)
+	if err != nil {
+		h.logger.Error("Failed to remove workspace member", zap.Error(err))
+		writeStoreError(w, err, "Failed to remove workspace member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	invitations, err := h.store.ListInvitations(ctx, userID, id)
+	if err != nil {
+		h.logger.Error("Failed to list workspace invitations", zap.Error(err))
+		writeStoreError(w, err, "Failed to get workspace invitations")
+		return
+	}
+
+	var resp = make([]InvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		resp[i] = h.newInvitationResponse(invitation)
+	}
+
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
+
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	var req InviteRequest
+	err := internal.ParseRequestBody(h.validator, r, &req)
+	if err != nil {
+		h.logger.Warn("Failed to parse request body", zap.Error(err))
+		internal.WriteParseError(w, err)
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = WorkspaceRoleVIEWER
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	invitation, err := h.store.Invite(ctx, userID, id, req.Email, req.Role)
+	if err != nil {
+		h.logger.Error("Failed to invite to workspace", zap.Error(err))
+		writeStoreError(w, err, "Failed to create invitation")
+		return
+	}
+
+	resp := h.newInvitationResponse(invitation)
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusCreated, resp)
+}
+
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathWorkspaceID(w, r)
+	if !ok {
+		return
+	}
+	invitationID, err := uuid.Parse(r.PathValue("invitationId"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	err = h.store.RevokeInvitation(ctx, userID, id, invitationID)
+	if err != nil {
+		h.logger.Error("Failed to revoke workspace invitation", zap.Error(err))
+		writeStoreError(w, err, "Failed to revoke invitation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation lets a user who already has an account join through an invitation link.
+// New users join automatically on their first login.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	invitationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := ctx.Value(jwt.UserContextKey).(uuid.UUID)
+
+	workspace, err := h.store.AcceptInvitation(ctx, userID, invitationID)
+	if err != nil {
+		h.logger.Error("Failed to accept workspace invitation", zap.Error(err))
+		writeStoreError(w, err, "Failed to accept invitation")
+		return
+	}
+
+	resp := Response{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		CreatedBy: workspace.CreatedBy.String(),
+		CreatedAt: workspace.CreatedAt.Time,
+		Role:      workspace.Role,
+	}
+	// Write response
+	internal.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,73 @@
+-- name: Create :one
+INSERT INTO workspaces (name, created_by)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetAllByMember :many
+SELECT w.id, w.name, w.created_by, w.created_at, m.role
+FROM workspaces w
+JOIN workspace_members m ON m.workspace_id = w.id
+WHERE m.user_id = $1
+ORDER BY w.id ASC;
+
+-- name: GetByIDForMember :one
+SELECT w.id, w.name, w.created_by, w.created_at, m.role
+FROM workspaces w
+JOIN workspace_members m ON m.workspace_id = w.id
+WHERE w.id = $1 AND m.user_id = $2;
+
+-- name: Rename :one
+UPDATE workspaces SET name = $2 WHERE id = $1 RETURNING *;
+
+-- name: Delete :exec
+DELETE FROM workspaces WHERE id = $1;
+
+-- name: AddMember :exec
+INSERT INTO workspace_members (workspace_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (workspace_id, user_id) DO NOTHING;
+
+-- name: GetMemberRole :one
+SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;
+
+-- name: ListMembers :many
+SELECT m.user_id, u.username, u.email, u.avatar_url, m.role, m.joined_at
+FROM workspace_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.workspace_id = $1
+ORDER BY m.joined_at ASC, u.username ASC;
+
+-- name: UpdateMemberRole :execrows
+UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2;
+
+-- name: RemoveMember :execrows
+DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2;
+
+-- name: CountOwners :one
+SELECT count(*) FROM workspace_members WHERE workspace_id = $1 AND role = 'OWNER';
+
+-- name: CreateInvitation :one
+INSERT INTO workspace_invitations (workspace_id, email, role, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (workspace_id, email) WHERE accepted_at IS NULL
+DO UPDATE SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, created_at = now(), expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListPendingInvitations :many
+SELECT * FROM workspace_invitations
+WHERE workspace_id = $1 AND accepted_at IS NULL AND expires_at > now()
+ORDER BY created_at ASC;
+
+-- name: DeleteInvitation :execrows
+DELETE FROM workspace_invitations WHERE id = $1 AND workspace_id = $2 AND accepted_at IS NULL;
+
+-- name: GetPendingInvitationForUpdate :one
+SELECT * FROM workspace_invitations
+WHERE id = $1 AND accepted_at IS NULL AND expires_at > now()
+FOR UPDATE;
+
+-- name: MarkInvitationAccepted :exec
+UPDATE workspace_invitations SET accepted_at = now() WHERE id = $1;
+
+-- name: GetUserEmail :one
+SELECT email FROM users WHERE id = $1;
@@ -0,0 +1,33 @@
+CREATE TYPE workspace_role AS ENUM ('OWNER', 'EDITOR', 'VIEWER');
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role workspace_role NOT NULL DEFAULT 'VIEWER',
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role workspace_role NOT NULL DEFAULT 'VIEWER',
+    invited_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS workspace_invitations_pending_idx
+    ON workspace_invitations (workspace_id, email) WHERE accepted_at IS NULL;
+CREATE INDEX IF NOT EXISTS workspace_invitations_email_idx ON workspace_invitations (email);
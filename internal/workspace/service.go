@@ -0,0 +1,381 @@
+package workspace
+
+import (
+	"advanced-backend/internal"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"slices"
+	"strings"
+	"time"
+)
+
+const InvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrNotFound       = errors.New("workspace not found")
+	ErrForbidden      = errors.New("insufficient workspace role")
+	ErrMemberNotFound = errors.New("workspace member not found")
+	// ErrLastOwner is returned when a change would leave the workspace without an owner
+	ErrLastOwner               = errors.New("workspace must keep at least one owner")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email")
+)
+
+type Service struct {
+	logger  *zap.Logger
+	db      *pgxpool.Pool
+	queries *Queries
+}
+
+func NewService(logger *zap.Logger, db *pgxpool.Pool) *Service {
+	return &Service{
+		logger:  logger,
+		db:      db,
+		queries: New(db),
+	}
+}
+
+func (s Service) GetAll(ctx context.Context, userID uuid.UUID) ([]GetAllByMemberRow, error) {
+	workspaces, err := s.queries.GetAllByMember(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get workspaces", zap.Error(err))
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+func (s Service) GetByID(ctx context.Context, userID uuid.UUID, id int32) (GetByIDForMemberRow, error) {
+	workspace, err := s.queries.GetByIDForMember(ctx, GetByIDForMemberParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return GetByIDForMemberRow{}, ErrNotFound
+		}
+		s.logger.Error("Failed to get workspace by ID", zap.Error(err))
+		return GetByIDForMemberRow{}, err
+	}
+	return workspace, nil
+}
+
+// Create creates a workspace with the actor as its first owner.
+func (s Service) Create(ctx context.Context, actorID uuid.UUID, name string) (Workspace, error) {
+	var workspace Workspace
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		workspace, err = q.Create(ctx, CreateParams{
+			Name:      name,
+			CreatedBy: actorID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.AddMember(ctx, AddMemberParams{
+			WorkspaceID: workspace.ID,
+			UserID:      actorID,
+			Role:        WorkspaceRoleOWNER,
+		})
+	})
+	if err != nil {
+		s.logger.Error("Failed to create workspace", zap.Error(err))
+		return Workspace{}, err
+	}
+
+	s.logger.Info("Created workspace", zap.Int32("workspace_id", workspace.ID), zap.String("owner_id", actorID.String()))
+	return workspace, nil
+}
+
+func (s Service) Rename(ctx context.Context, actorID uuid.UUID, id int32, name string) (Workspace, error) {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER)
+	if err != nil {
+		s.logWriteError("Failed to rename workspace", err)
+		return Workspace{}, err
+	}
+
+	workspace, err := s.queries.Rename(ctx, RenameParams{
+		ID:   id,
+		Name: name,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Workspace{}, ErrNotFound
+		}
+		s.logger.Error("Failed to rename workspace", zap.Error(err))
+		return Workspace{}, err
+	}
+	return workspace, nil
+}
+
+// Delete removes the workspace together with its members, invitations and tasks.
+func (s Service) Delete(ctx context.Context, actorID uuid.UUID, id int32) error {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER)
+	if err != nil {
+		s.logWriteError("Failed to delete workspace", err)
+		return err
+	}
+
+	err = s.queries.Delete(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to delete workspace", zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Deleted workspace", zap.Int32("workspace_id", id), zap.String("actor_id", actorID.String()))
+	return nil
+}
+
+func (s Service) ListMembers(ctx context.Context, actorID uuid.UUID, id int32) ([]ListMembersRow, error) {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER, WorkspaceRoleEDITOR, WorkspaceRoleVIEWER)
+	if err != nil {
+		s.logWriteError("Failed to list workspace members", err)
+		return nil, err
+	}
+
+	members, err := s.queries.ListMembers(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list workspace members", zap.Error(err))
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s Service) UpdateMemberRole(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID, role WorkspaceRole) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		err := requireRole(ctx, q, id, actorID, WorkspaceRoleOWNER)
+		if err != nil {
+			return err
+		}
+
+		if role != WorkspaceRoleOWNER {
+			err = checkNotLastOwner(ctx, q, id, userID)
+			if err != nil {
+				return err
+			}
+		}
+
+		rows, err := q.UpdateMemberRole(ctx, UpdateMemberRoleParams{
+			WorkspaceID: id,
+			UserID:      userID,
+			Role:        role,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		s.logWriteError("Failed to update workspace member role", err)
+		return err
+	}
+	return nil
+}
+
+// RemoveMember removes a member from the workspace. Owners can remove anyone and every
+// member can remove themselves.
+func (s Service) RemoveMember(ctx context.Context, actorID uuid.UUID, id int32, userID uuid.UUID) error {
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		var err error
+		if actorID == userID {
+			err = requireRole(ctx, q, id, actorID, WorkspaceRoleOWNER, WorkspaceRoleEDITOR, WorkspaceRoleVIEWER)
+		} else {
+			err = requireRole(ctx, q, id, actorID, WorkspaceRoleOWNER)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = checkNotLastOwner(ctx, q, id, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := q.RemoveMember(ctx, RemoveMemberParams{
+			WorkspaceID: id,
+			UserID:      userID,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		s.logWriteError("Failed to remove workspace member", err)
+		return err
+	}
+	return nil
+}
+
+// Invite invites an email address to the workspace. Inviting the same address again
+// replaces the pending invitation.
+func (s Service) Invite(ctx context.Context, actorID uuid.UUID, id int32, email string, role WorkspaceRole) (WorkspaceInvitation, error) {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER)
+	if err != nil {
+		s.logWriteError("Failed to invite to workspace", err)
+		return WorkspaceInvitation{}, err
+	}
+
+	invitation, err := s.queries.CreateInvitation(ctx, CreateInvitationParams{
+		WorkspaceID: id,
+		Email:       strings.ToLower(email),
+		Role:        role,
+		InvitedBy:   actorID,
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(InvitationTTL), Valid: true},
+	})
+	if err != nil {
+		s.logger.Error("Failed to create workspace invitation", zap.Error(err))
+		return WorkspaceInvitation{}, err
+	}
+
+	s.logger.Info("Invited to workspace", zap.Int32("workspace_id", id), zap.String("invitation_id", invitation.ID.String()))
+	return invitation, nil
+}
+
+func (s Service) ListInvitations(ctx context.Context, actorID uuid.UUID, id int32) ([]WorkspaceInvitation, error) {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER)
+	if err != nil {
+		s.logWriteError("Failed to list workspace invitations", err)
+		return nil, err
+	}
+
+	invitations, err := s.queries.ListPendingInvitations(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to list workspace invitations", zap.Error(err))
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s Service) RevokeInvitation(ctx context.Context, actorID uuid.UUID, id int32, invitationID uuid.UUID) error {
+	err := requireRole(ctx, s.queries, id, actorID, WorkspaceRoleOWNER)
+	if err != nil {
+		s.logWriteError("Failed to revoke workspace invitation", err)
+		return err
+	}
+
+	rows, err := s.queries.DeleteInvitation(ctx, DeleteInvitationParams{
+		ID:          invitationID,
+		WorkspaceID: id,
+	})
+	if err != nil {
+		s.logger.Error("Failed to revoke workspace invitation", zap.Error(err))
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the actor to the invited workspace. The invitation must have been
+// sent to the actor's email address.
+func (s Service) AcceptInvitation(ctx context.Context, actorID uuid.UUID, invitationID uuid.UUID) (GetByIDForMemberRow, error) {
+	var workspace GetByIDForMemberRow
+	err := internal.WithTxQueries(ctx, s.db, s.queries, func(q *Queries) error {
+		invitation, err := q.GetPendingInvitationForUpdate(ctx, invitationID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+
+		email, err := q.GetUserEmail(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(email, invitation.Email) {
+			return ErrInvitationEmailMismatch
+		}
+
+		err = q.AddMember(ctx, AddMemberParams{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      actorID,
+			Role:        invitation.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.MarkInvitationAccepted(ctx, invitation.ID)
+		if err != nil {
+			return err
+		}
+
+		workspace, err = q.GetByIDForMember(ctx, GetByIDForMemberParams{
+			ID:     invitation.WorkspaceID,
+			UserID: actorID,
+		})
+		return err
+	})
+	if err != nil {
+		s.logWriteError("Failed to accept workspace invitation", err)
+		return GetByIDForMemberRow{}, err
+	}
+
+	s.logger.Info("Accepted workspace invitation", zap.Int32("workspace_id", workspace.ID), zap.String("user_id", actorID.String()))
+	return workspace, nil
+}
+
+func (s Service) logWriteError(message string, err error) {
+	internal.LogWriteError(s.logger, message, err,
+		ErrNotFound, ErrForbidden, ErrMemberNotFound, ErrLastOwner, ErrInvitationNotFound, ErrInvitationEmailMismatch)
+}
+
+// requireRole makes sure the user is a member of the workspace with one of the given roles.
+// Non-members get ErrNotFound so that workspaces stay invisible to outsiders.
+func requireRole(ctx context.Context, q *Queries, id int32, userID uuid.UUID, roles ...WorkspaceRole) error {
+	role, err := q.GetMemberRole(ctx, GetMemberRoleParams{
+		WorkspaceID: id,
+		UserID:      userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if !slices.Contains(roles, role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkNotLastOwner returns ErrLastOwner when the user is the only owner of the workspace.
+func checkNotLastOwner(ctx context.Context, q *Queries, id int32, userID uuid.UUID) error {
+	role, err := q.GetMemberRole(ctx, GetMemberRoleParams{
+		WorkspaceID: id,
+		UserID:      userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if role != WorkspaceRoleOWNER {
+		return nil
+	}
+
+	owners, err := q.CountOwners(ctx, id)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}